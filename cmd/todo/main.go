@@ -3,40 +3,47 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/user"
+	"strings"
+	"time"
 
 	"example.com/todo/internal/cli"
 	"example.com/todo/internal/storage"
 	"example.com/todo/internal/todo"
 )
 
-const defaultFilename = "data/todos.json"
+const (
+	defaultFilename    = "data/todos.json"
+	defaultLockTimeout = "5s"
+)
 
 func main() {
-	if len(os.Args) < 2 {
+	// Global flags may appear anywhere on the command line.
+	args := os.Args[1:]
+	filename, args := extractGlobalFlag(args, "file", defaultFilename)
+	output, args := extractGlobalFlag(args, "output", "table")
+	store, args := extractGlobalFlag(args, "store", "")
+	lockTimeout, args := extractGlobalFlag(args, "lock-timeout", defaultLockTimeout)
+
+	if len(args) == 0 {
 		cli.PrintUsage()
 		os.Exit(1)
 	}
 
-	command := os.Args[1]
-	args := os.Args[2:]
+	if err := cli.SetOutputFormat(output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
 
-	// Parse global flags - only look for -file flag.
-	filename := defaultFilename
+	command := args[0]
+	args = args[1:]
 
-	// Look for -file flag in args.
-	for i := 0; i < len(args); i++ {
-		if args[i] == "-file" && i+1 < len(args) {
-			filename = args[i+1]
-			// Remove -file and its value from args.
-			args = append(args[:i], args[i+2:]...)
-			break
-		}
+	timeout, err := time.ParseDuration(lockTimeout)
+	if err != nil || timeout < 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid lock timeout %q (expected e.g. 5s or 500ms)\n", lockTimeout)
+		os.Exit(1)
 	}
 
-	// Initialize dependencies.
-	repo := storage.NewJSONRepository(filename)
-	service := todo.NewService(repo)
-
 	// Get available commands.
 	commands := cli.GetCommands()
 
@@ -47,8 +54,91 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := cmd.Execute(service, args); err != nil {
+	// Initialize dependencies.
+	service, closeStore, err := openStore(store, filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	service.SetActor(actor())
+	service.SetLockTimeout(timeout)
+
+	// A change that conflicts with another process's is made again on the
+	// reloaded todos by the service itself, so the command runs once.
+	err = cmd.Execute(service, args)
+	if closeErr := closeStore(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(1)
 	}
 }
+
+// openStore opens the store selected by -store, "json:<file>",
+// "sqlite:<file>" or "events:<file>", or else the JSON file given by -file,
+// and creates the service on it. The returned function releases the store
+// once the command is done.
+func openStore(spec, filename string) (*todo.Service, func() error, error) {
+	kind, path := "json", filename
+	if spec != "" {
+		var found bool
+		if kind, path, found = strings.Cut(spec, ":"); !found || path == "" {
+			return nil, nil, fmt.Errorf("invalid store %q (expected json:<file>, sqlite:<file> or events:<file>)", spec)
+		}
+	}
+
+	switch kind {
+	case "json":
+		// The service locks the store while a mutation loads, changes and
+		// saves the todos, so that concurrent invocations do not overwrite
+		// each other's changes.
+		return todo.NewService(storage.NewJSONRepository(path)), noClose, nil
+	case "sqlite":
+		// SQLite serializes writes itself, and a save that conflicts with
+		// another invocation's is retried.
+		repo, err := storage.NewSQLiteRepository(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return todo.NewService(repo), repo.Close, nil
+	case "events":
+		// Like the JSON files next to it, the event log is locked by the
+		// service while it is changed.
+		return todo.NewStoreService(storage.NewEventLogRepository(path)), noClose, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown store %q (expected json:<file>, sqlite:<file> or events:<file>)", spec)
+	}
+}
+
+// noClose is the function releasing stores that hold nothing open.
+func noClose() error {
+	return nil
+}
+
+// actor returns who is making changes, as recorded in the history: $TODO_USER,
+// or else the login name.
+func actor() string {
+	if name := os.Getenv("TODO_USER"); name != "" {
+		return name
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return ""
+}
+
+// extractGlobalFlag removes the first "-name value" or "-name=value" from
+// args and returns its value, or fallback when the flag is absent.
+func extractGlobalFlag(args []string, name, fallback string) (string, []string) {
+	for i, arg := range args {
+		if arg == "-"+name && i+1 < len(args) {
+			value := args[i+1]
+			return value, append(args[:i:i], args[i+2:]...)
+		}
+		if value, ok := strings.CutPrefix(arg, "-"+name+"="); ok {
+			return value, append(args[:i:i], args[i+1:]...)
+		}
+	}
+	return fallback, args
+}
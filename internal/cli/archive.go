@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+// ArchiveCommand handles the archive command.
+func ArchiveCommand(service *todo.Service, args []string) error {
+	flagSet := flag.NewFlagSet("archive", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo archive [OPTIONS]\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
+	}
+
+	beforeDate := flagSet.String("before", "", "Only archive todos completed before this date")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	before := time.Now()
+	if *beforeDate != "" {
+		date, err := todo.ParseDate(*beforeDate, before)
+		if err != nil {
+			return err
+		}
+		before = date
+	}
+
+	archived, err := service.Archive(before)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		events := make([]record, len(archived))
+		for i, todoItem := range archived {
+			events[i] = todoEvent(service, actionArchived, todoItem)
+		}
+		return printRecords(events, todoEvent(service, actionArchived, todo.Todo{}))
+	}
+
+	fmt.Printf("Archived %d completed todo(s)\n", len(archived))
+	return nil
+}
@@ -4,9 +4,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"text/template"
+	"time"
 
 	"example.com/todo/internal/todo"
 )
@@ -31,11 +34,46 @@ func GetCommands() map[string]Command {
 			Description: "List todos",
 			Execute:     ListCommand,
 		},
+		"search": {
+			Name:        "search",
+			Description: "Search todos",
+			Execute:     SearchCommand,
+		},
+		"show": {
+			Name:        "show",
+			Description: "Show all details of a todo",
+			Execute:     ShowCommand,
+		},
+		"note": {
+			Name:        "note",
+			Description: "Add a note to a todo",
+			Execute:     NoteCommand,
+		},
 		"complete": {
 			Name:        "complete",
 			Description: "Mark a todo as completed",
 			Execute:     CompleteCommand,
 		},
+		"edit": {
+			Name:        "edit",
+			Description: "Edit a todo",
+			Execute:     EditCommand,
+		},
+		"prioritize": {
+			Name:        "prioritize",
+			Description: "Set the priority of a todo",
+			Execute:     PrioritizeCommand,
+		},
+		"block": {
+			Name:        "block",
+			Description: "Mark a todo as blocked by another todo",
+			Execute:     BlockCommand,
+		},
+		"unblock": {
+			Name:        "unblock",
+			Description: "Remove a blocker from a todo",
+			Execute:     UnblockCommand,
+		},
 		"incomplete": {
 			Name:        "incomplete",
 			Description: "Mark a todo as not completed",
@@ -46,11 +84,71 @@ func GetCommands() map[string]Command {
 			Description: "Delete a todo",
 			Execute:     DeleteCommand,
 		},
+		"undo": {
+			Name:        "undo",
+			Description: "Undo the last changes",
+			Execute:     UndoCommand,
+		},
+		"redo": {
+			Name:        "redo",
+			Description: "Redo the last undone changes",
+			Execute:     RedoCommand,
+		},
+		"trash": {
+			Name:        "trash",
+			Description: "Manage deleted todos",
+			Execute:     TrashCommand,
+		},
+		"restore": {
+			Name:        "restore",
+			Description: "Restore a deleted todo",
+			Execute:     RestoreCommand,
+		},
+		"archive": {
+			Name:        "archive",
+			Description: "Move completed todos to the archive",
+			Execute:     ArchiveCommand,
+		},
+		"history": {
+			Name:        "history",
+			Description: "Show the change history of a todo",
+			Execute:     HistoryCommand,
+		},
+		"log": {
+			Name:        "log",
+			Description: "Show the change history of all todos",
+			Execute:     LogCommand,
+		},
+		"compact": {
+			Name:        "compact",
+			Description: "Fold the event log into a snapshot",
+			Execute:     CompactCommand,
+		},
 		"stats": {
 			Name:        "stats",
 			Description: "Show todo statistics",
 			Execute:     StatsCommand,
 		},
+		"project": {
+			Name:        "project",
+			Description: "Manage projects",
+			Execute:     ProjectCommand,
+		},
+		"recur": {
+			Name:        "recur",
+			Description: "Manage recurring todos",
+			Execute:     RecurCommand,
+		},
+		"tags": {
+			Name:        "tags",
+			Description: "List tags with their todo counts",
+			Execute:     TagsCommand,
+		},
+		"schema": {
+			Name:        "schema",
+			Description: "Show the JSON Schema of the structured output formats",
+			Execute:     SchemaCommand,
+		},
 		"help": {
 			Name:        "help",
 			Description: "Show help information",
@@ -66,55 +164,254 @@ func GetCommands() map[string]Command {
 
 // AddCommand handles the add command.
 func AddCommand(service *todo.Service, args []string) error {
-	if len(args) == 0 {
+	flagSet := flag.NewFlagSet("add", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo add [OPTIONS] <description>\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
+	}
+
+	priorityName := flagSet.String("priority", "none", "Priority level (none, low, medium, high, urgent)")
+	due := flagSet.String("due", "", "Due date (YYYY-MM-DD, \"tomorrow\", \"next friday\", \"in 3 days\", ...)")
+	var tags stringList
+	flagSet.Var(&tags, "tag", "Tag to attach (repeatable)")
+	project := flagSet.String("project", "", "Project the todo belongs to")
+	parentID := flagSet.Int("parent", 0, "ID of the parent todo, making this a subtask")
+	every := flagSet.String("every", "", "Recurrence (daily, weekday, \"weekly mon\", \"monthly 1\", yearly or an RRULE)")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	if flagSet.NArg() == 0 {
 		return fmt.Errorf("description is required")
 	}
 
-	description := strings.Join(args, " ")
-	todoItem, err := service.Add(description)
+	priority, err := todo.ParsePriority(*priorityName)
 	if err != nil {
 		return err
 	}
 
+	opts := []todo.AddOption{todo.WithPriority(priority)}
+	if *due != "" {
+		dueAt, err := todo.ParseDate(*due, time.Now())
+		if err != nil {
+			return err
+		}
+		opts = append(opts, todo.WithDueAt(dueAt))
+	}
+
+	if *project != "" {
+		opts = append(opts, todo.WithProject(*project))
+	}
+	if *parentID != 0 {
+		opts = append(opts, todo.WithParent(*parentID))
+	}
+	if *every != "" {
+		rule, err := todo.ParseRecurrence(*every)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, todo.WithRecurrence(rule))
+	}
+
+	description, descriptionTags := todo.ExtractTags(strings.Join(flagSet.Args(), " "))
+	opts = append(opts, todo.WithTags(append(tags, descriptionTags...)...))
+
+	todoItem, err := service.Add(description, opts...)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printRecords([]record{todoEvent(service, actionAdded, *todoItem)}, todoEvent(service, actionAdded, todo.Todo{}))
+	}
 	fmt.Printf("Added todo #%d: %s\n", todoItem.ID, todoItem.Description)
 	return nil
 }
 
 // ListCommand handles the list command.
 func ListCommand(service *todo.Service, args []string) error {
-	var filterCompleted *bool
-
 	flagSet := flag.NewFlagSet("list", flag.ExitOnError)
 	flagSet.Usage = func() {
-		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo list [OPTIONS]\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo list [OPTIONS] [FILTER]\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Filter example: 'status:pending and (tag:infra or priority>=high) and due<+7d'\n")
 		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
 		flagSet.PrintDefaults()
 	}
 
 	showAll := flagSet.Bool("all", false, "Show all todos")
-	completed := flagSet.Bool("completed", false, "Show only completed todos")
-	pending := flagSet.Bool("pending", false, "Show only pending todos")
+	completed := flagSet.Bool("completed", false, "Show only completed todos (same as status:completed)")
+	pending := flagSet.Bool("pending", false, "Show only pending todos (same as status:pending)")
+	minPriority := flagSet.String("priority", "", "Show only todos with at least this priority")
+	byPriority := flagSet.Bool("by-priority", false, "Sort by priority, highest first (same as -sort -priority)")
+	sortSpec := flagSet.String("sort", "", "Sort by comma-separated fields: id, created, completed, due, priority, description (prefix with - for descending)")
+	overdue := flagSet.Bool("overdue", false, "Show only overdue todos")
+	dueToday := flagSet.Bool("due-today", false, "Show only todos due today")
+	dueBefore := flagSet.String("due-before", "", "Show only todos due before this date")
+	var allTags, anyTags stringList
+	flagSet.Var(&allTags, "tag", "Show only todos with this tag (repeatable, all must match)")
+	flagSet.Var(&anyTags, "any-tag", "Show only todos with this tag (repeatable, any may match)")
+	project := flagSet.String("project", "", "Show only todos in this project")
+	tree := flagSet.Bool("tree", false, "Show subtasks indented under their parent")
+	ready := flagSet.Bool("ready", false, "Show only pending todos without open blockers")
+	blocked := flagSet.Bool("blocked", false, "Show only pending todos with open blockers")
+	format := flagSet.String("format", "", "Go text/template rendering each todo, or @file to read it from a file")
+	archived := flagSet.Bool("archived", false, "Show archived todos instead of active ones")
+	asOf := flagSet.String("as-of", "", "Show todos as they were at this time (e.g. 2024-05-01, \"2024-05-01 15:04\", 7d); needs an events store")
+	limit := flagSet.Int("limit", 0, "Show at most this many todos")
+	offset := flagSet.Int("offset", 0, "Skip this many todos")
 
-	if err := flagSet.Parse(args); err != nil {
+	positional, err := parseInterspersed(flagSet, args)
+	if err != nil {
 		return fmt.Errorf("failed to parse flags: %w", err)
 	}
 
+	var tmpl *template.Template
+	if *format != "" {
+		if structuredOutput() {
+			return fmt.Errorf("cannot use -format with -output %s", outputFormat)
+		}
+		if tmpl, err = parseListTemplate(*format); err != nil {
+			return err
+		}
+	}
+
 	// Determine filter.
 	if *completed && *pending {
 		return fmt.Errorf("cannot use both -completed and -pending flags")
 	}
-	if *completed {
-		filterCompleted = completed
-	} else if *pending {
-		falseVal := false
-		filterCompleted = &falseVal
+	if *archived && *asOf != "" {
+		return fmt.Errorf("cannot use both -archived and -as-of flags")
+	}
+	if *ready && *blocked {
+		return fmt.Errorf("cannot use both -ready and -blocked flags")
+	}
+	if *limit < 0 || *offset < 0 {
+		return fmt.Errorf("-limit and -offset cannot be negative")
+	}
+
+	// Status, tags, due dates, the project and sorting are left to the store, so that
+	// it only reads the todos listed.
+	var opts todo.ListOptions
+	if !*showAll {
+		if *completed {
+			opts.Status = todo.StatusCompleted
+		} else if *pending {
+			opts.Status = todo.StatusPending
+		}
+	}
+	now := time.Now()
+	if *overdue {
+		if opts.Status == todo.StatusCompleted {
+			return fmt.Errorf("cannot use both -completed and -overdue flags")
+		}
+		// Overdue todos are pending and were due before today.
+		opts.Status = todo.StatusPending
+		today := todo.StartOfDay(now)
+		narrowDue(&opts, nil, &today)
+	}
+	if *dueToday {
+		start := todo.StartOfDay(now)
+		end := start.AddDate(0, 0, 1)
+		narrowDue(&opts, &start, &end)
+	}
+	if *dueBefore != "" {
+		before, err := todo.ParseDate(*dueBefore, now)
+		if err != nil {
+			return err
+		}
+		narrowDue(&opts, nil, &before)
+	}
+	opts.Tags = allTags
+	if *project != "" {
+		if _, err := service.GetProject(*project); err != nil {
+			return err
+		}
+		opts.Project = *project
+	}
+	if *sortSpec != "" {
+		if opts.Sort, err = todo.ParseSortKeys(*sortSpec); err != nil {
+			return err
+		}
+	}
+	if *byPriority {
+		opts.Sort = append([]todo.SortKey{{Field: "priority", Descending: true}}, opts.Sort...)
+	}
+
+	// The other filters are applied here, so the store can only page the
+	// todos without them.
+	expression := strings.Join(positional, " ")
+	filtersHere := expression != "" || *minPriority != "" || len(anyTags) > 0 || *ready || *blocked
+	page := todo.ListOptions{Offset: *offset, Limit: *limit}
+	if !filtersHere {
+		opts.Offset, opts.Limit = page.Offset, page.Limit
+		page = todo.ListOptions{}
 	}
 
 	var todos []todo.Todo
-	if filterCompleted != nil && !*showAll {
-		todos = service.GetByStatus(*filterCompleted)
-	} else {
-		todos = service.GetAll()
+	switch {
+	case *asOf != "":
+		at, err := parseAsOf(*asOf, now)
+		if err != nil {
+			return err
+		}
+		if todos, err = service.GetAllAsOf(at); err != nil {
+			return err
+		}
+		if todos, err = opts.Apply(todos); err != nil {
+			return err
+		}
+	case *archived:
+		if todos, err = service.GetArchived(); err != nil {
+			return err
+		}
+		if todos, err = opts.Apply(todos); err != nil {
+			return err
+		}
+	default:
+		if todos, err = service.List(opts); err != nil {
+			return err
+		}
+	}
+
+	if expression != "" {
+		query, err := todo.ParseQuery(expression, now)
+		if err != nil {
+			return queryError(expression, err)
+		}
+		if todos, err = service.FilterQuery(todos, query); err != nil {
+			return err
+		}
+	}
+
+	if *minPriority != "" {
+		priority, err := todo.ParsePriority(*minPriority)
+		if err != nil {
+			return err
+		}
+		todos = todo.FilterByPriority(todos, priority)
+	}
+	if len(anyTags) > 0 {
+		todos = todo.FilterByAnyTag(todos, anyTags)
+	}
+	if *ready {
+		if todos, err = service.FilterReady(todos); err != nil {
+			return err
+		}
+	}
+	if *blocked {
+		if todos, err = service.FilterBlocked(todos); err != nil {
+			return err
+		}
+	}
+	todos = page.Page(todos)
+
+	if structuredOutput() {
+		return printRecords(todoRecords(service, todos), todoRecord(service, todo.Todo{}))
+	}
+	if tmpl != nil {
+		return printTemplate(tmpl, todos)
 	}
 
 	if len(todos) == 0 {
@@ -124,18 +421,66 @@ func ListCommand(service *todo.Service, args []string) error {
 
 	// Create tabwriter for aligned output.
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	if _, err := fmt.Fprintln(w, "ID\tStatus\tDescription\tCreated"); err != nil {
+	if _, err := fmt.Fprintln(w, "ID\tStatus\tPriority\tDue\tProject\tDescription\tTags\tCreated"); err != nil {
 		return err
 	}
 
-	for _, todoItem := range todos {
+	nodes := make([]todo.TreeNode, 0, len(todos))
+	if *tree {
+		nodes = todo.BuildTree(todos)
+	} else {
+		for _, todoItem := range todos {
+			nodes = append(nodes, todo.TreeNode{Todo: todoItem})
+		}
+	}
+
+	for _, node := range nodes {
+		todoItem := node.Todo
 		status := "[ ]"
 		if todoItem.Completed {
 			status = "[✓]"
 		}
 
+		priority := "-"
+		if todoItem.Priority != todo.PriorityNone {
+			priority = todoItem.Priority.String()
+		}
+
+		dueDate := "-"
+		if todoItem.DueAt != nil {
+			dueDate = todoItem.DueAt.Format(todo.DateFormat)
+			if todoItem.IsOverdue(now) {
+				dueDate += " (overdue)"
+			}
+		}
+
+		tagList := "-"
+		if len(todoItem.Tags) > 0 {
+			tagList = strings.Join(todoItem.Tags, ",")
+		}
+
+		projectName := "-"
+		if todoItem.Project != "" {
+			projectName = todoItem.Project
+		}
+
+		description := todoItem.Description
+		if node.Depth > 0 {
+			description = strings.Repeat("  ", node.Depth-1) + "└─ " + description
+		}
+		if todoItem.Recurrence != nil && !todoItem.Completed {
+			description += " ↻"
+		}
+		blockers, err := service.OpenBlockers(todoItem)
+		if err != nil {
+			return err
+		}
+		if len(blockers) > 0 {
+			description += fmt.Sprintf(" (blocked by %s)", todo.JoinIDs(blockers, ", "))
+		}
+
 		created := todoItem.CreatedAt.Format("2006-01-02 15:04")
-		if _, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", todoItem.ID, status, todoItem.Description, created); err != nil {
+		if _, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", todoItem.ID, status, priority, dueDate, projectName, description, tagList, created); err != nil {
 			return err
 		}
 	}
@@ -143,10 +488,103 @@ func ListCommand(service *todo.Service, args []string) error {
 	return w.Flush()
 }
 
+// narrowDue limits the due range selected by opts to the todos due at or
+// after from and before before, either of which may be nil.
+func narrowDue(opts *todo.ListOptions, from, before *time.Time) {
+	if from != nil && (opts.DueFrom == nil || from.After(*opts.DueFrom)) {
+		opts.DueFrom = from
+	}
+	if before != nil && (opts.DueBefore == nil || before.Before(*opts.DueBefore)) {
+		opts.DueBefore = before
+	}
+}
+
 // CompleteCommand handles the complete command.
 func CompleteCommand(service *todo.Service, args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("todo ID is required")
+	flagSet := flag.NewFlagSet("complete", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo complete [OPTIONS] <id|from-to>...\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
+	}
+
+	cascade := flagSet.Bool("cascade", false, "Also complete all open subtasks")
+	force := flagSet.Bool("force", false, "Complete even if subtasks or blockers are still open")
+	where := flagSet.String("where", "", "Also complete the todos matching this filter expression")
+
+	args, err := parseInterspersed(flagSet, args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	ids, err := selectIDs(service, args, *where)
+	if err != nil {
+		return err
+	}
+
+	var opts []todo.CompleteOption
+	if *cascade {
+		opts = append(opts, todo.CompleteCascade())
+	}
+	if *force {
+		opts = append(opts, todo.CompleteForce())
+	}
+
+	// Remember which subtasks a cascade will complete, to report them.
+	completedIDs := slices.Clone(ids)
+	if *cascade {
+		for _, id := range ids {
+			descendants, err := service.GetDescendants(id)
+			if err != nil {
+				return err
+			}
+			for _, descendant := range descendants {
+				if !descendant.Completed && !slices.Contains(completedIDs, descendant.ID) {
+					completedIDs = append(completedIDs, descendant.ID)
+				}
+			}
+		}
+	}
+
+	if err := service.CompleteAll(ids, opts...); err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		var events []record
+		for _, completedID := range completedIDs {
+			if completed, err := service.Get(completedID); err == nil {
+				events = append(events, todoEvent(service, actionCompleted, completed))
+			}
+		}
+		for _, id := range ids {
+			if completed, err := service.Get(id); err == nil && completed.Recurrence != nil {
+				if next, err := service.GetOpenInstance(completed.SeriesID); err == nil {
+					events = append(events, todoEvent(service, actionAdded, next))
+				}
+			}
+		}
+		return printRecords(events, todoEvent(service, actionCompleted, todo.Todo{}))
+	}
+
+	for _, id := range ids {
+		fmt.Printf("Marked todo #%d as completed\n", id)
+
+		if completed, err := service.Get(id); err == nil && completed.Recurrence != nil {
+			if next, err := service.GetOpenInstance(completed.SeriesID); err == nil {
+				fmt.Printf("Next occurrence: todo #%d due %s\n", next.ID, next.DueAt.Format(todo.DateFormat))
+			} else {
+				fmt.Println("The recurring series has ended")
+			}
+		}
+	}
+	return nil
+}
+
+// PrioritizeCommand handles the prioritize command.
+func PrioritizeCommand(service *todo.Service, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("todo ID and priority level are required")
 	}
 
 	id, err := strconv.Atoi(args[0])
@@ -154,66 +592,213 @@ func CompleteCommand(service *todo.Service, args []string) error {
 		return fmt.Errorf("invalid todo ID: %s", args[0])
 	}
 
-	if err := service.Complete(id); err != nil {
+	priority, err := todo.ParsePriority(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := service.SetPriority(id, priority); err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printTodoEvent(service, actionUpdated, id)
+	}
+
+	fmt.Printf("Set priority of todo #%d to %s\n", id, priority)
+	return nil
+}
+
+// BlockCommand handles the block command.
+func BlockCommand(service *todo.Service, args []string) error {
+	id, blockerID, err := parseIDPair(args)
+	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Marked todo #%d as completed\n", id)
+	if err := service.Block(id, blockerID); err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printTodoEvent(service, actionBlocked, id)
+	}
+
+	fmt.Printf("Todo #%d is now blocked by todo #%d\n", id, blockerID)
+	return nil
+}
+
+// UnblockCommand handles the unblock command.
+func UnblockCommand(service *todo.Service, args []string) error {
+	id, blockerID, err := parseIDPair(args)
+	if err != nil {
+		return err
+	}
+
+	if err := service.Unblock(id, blockerID); err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printTodoEvent(service, actionUnblocked, id)
+	}
+
+	fmt.Printf("Todo #%d is no longer blocked by todo #%d\n", id, blockerID)
 	return nil
 }
 
 // IncompleteCommand handles the incomplete command.
 func IncompleteCommand(service *todo.Service, args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("todo ID is required")
+	flagSet := flag.NewFlagSet("incomplete", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo incomplete [OPTIONS] <id|from-to>...\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
 	}
 
-	id, err := strconv.Atoi(args[0])
+	where := flagSet.String("where", "", "Also mark the todos matching this filter expression as incomplete")
+
+	args, err := parseInterspersed(flagSet, args)
 	if err != nil {
-		return fmt.Errorf("invalid todo ID: %s", args[0])
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	ids, err := selectIDs(service, args, *where)
+	if err != nil {
+		return err
 	}
 
-	if err := service.Incomplete(id); err != nil {
+	if err := service.IncompleteAll(ids); err != nil {
 		return err
 	}
 
-	fmt.Printf("Marked todo #%d as incomplete\n", id)
+	if structuredOutput() {
+		var events []record
+		for _, id := range ids {
+			if todoItem, err := service.Get(id); err == nil {
+				events = append(events, todoEvent(service, actionIncomplete, todoItem))
+			}
+		}
+		return printRecords(events, todoEvent(service, actionIncomplete, todo.Todo{}))
+	}
+
+	for _, id := range ids {
+		fmt.Printf("Marked todo #%d as incomplete\n", id)
+	}
 	return nil
 }
 
 // DeleteCommand handles the delete command.
 func DeleteCommand(service *todo.Service, args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("todo ID is required")
+	flagSet := flag.NewFlagSet("delete", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo delete [OPTIONS] <id|from-to>...\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
 	}
 
-	id, err := strconv.Atoi(args[0])
+	cascade := flagSet.Bool("cascade", false, "Also delete all subtasks instead of moving them up")
+	where := flagSet.String("where", "", "Also delete the todos matching this filter expression")
+
+	args, err := parseInterspersed(flagSet, args)
 	if err != nil {
-		return fmt.Errorf("invalid todo ID: %s", args[0])
+		return fmt.Errorf("failed to parse flags: %w", err)
 	}
 
-	// Get todo details before deletion for confirmation
-	todoItem, err := service.GetByID(id)
+	ids, err := selectIDs(service, args, *where)
 	if err != nil {
 		return err
 	}
 
-	if err := service.Delete(id); err != nil {
+	// Records are built before deletion, while blockers can still be
+	// resolved.
+	var events []record
+	var deleted []todo.Todo
+	seen := make(map[int]bool)
+	for _, id := range ids {
+		todoItem, err := service.Get(id)
+		if err != nil {
+			return err
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		deleted = append(deleted, todoItem)
+		events = append(events, todoEvent(service, actionDeleted, todoItem))
+	}
+
+	var opts []todo.DeleteOption
+	if *cascade {
+		opts = append(opts, todo.DeleteCascade())
+		for _, id := range ids {
+			descendants, err := service.GetDescendants(id)
+			if err != nil {
+				return err
+			}
+			for _, descendant := range descendants {
+				if !seen[descendant.ID] {
+					seen[descendant.ID] = true
+					events = append(events, todoEvent(service, actionDeleted, descendant))
+				}
+			}
+		}
+	}
+
+	if err := service.DeleteAll(ids, opts...); err != nil {
 		return err
 	}
 
-	fmt.Printf("Deleted todo #%d: %s\n", id, todoItem.Description)
+	if structuredOutput() {
+		return printRecords(events, todoEvent(service, actionDeleted, todo.Todo{}))
+	}
+
+	for _, todoItem := range deleted {
+		fmt.Printf("Deleted todo #%d: %s (restore it with \"todo restore %d\")\n", todoItem.ID, todoItem.Description, todoItem.ID)
+	}
 	return nil
 }
 
 // StatsCommand handles the stats command.
 func StatsCommand(service *todo.Service, args []string) error {
-	stats := service.GetStats()
+	flagSet := flag.NewFlagSet("stats", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo stats [OPTIONS]\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
+	}
+
+	byProject := flagSet.Bool("by-project", false, "Break statistics down per project")
+	includeArchive := flagSet.Bool("include-archive", false, "Include archived todos")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	if *byProject {
+		if *includeArchive {
+			return fmt.Errorf("cannot use both -by-project and -include-archive flags")
+		}
+		return printStatsByProject(service)
+	}
+
+	getStats := service.GetStats
+	if *includeArchive {
+		getStats = service.GetStatsIncludingArchive
+	}
+	stats, err := getStats()
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return printRecord(statsRecord(stats))
+	}
 
 	fmt.Printf("Todo Statistics:\n")
 	fmt.Printf("  Total: %d\n", stats.Total)
 	fmt.Printf("  Completed: %d\n", stats.Completed)
 	fmt.Printf("  Pending: %d\n", stats.Pending)
+	fmt.Printf("  Overdue: %d\n", stats.Overdue)
 
 	if stats.Total > 0 {
 		fmt.Printf("  Completion Rate: %.1f%%\n", stats.CompletionRate())
@@ -222,8 +807,77 @@ func StatsCommand(service *todo.Service, args []string) error {
 	return nil
 }
 
+func printStatsByProject(service *todo.Service) error {
+	byProject, err := service.GetStatsByProject()
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		var records []record
+		for _, stats := range byProject {
+			records = append(records, projectStatsRecord(stats))
+		}
+		return printRecords(records, projectStatsRecord(todo.ProjectStats{}))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, "Project\tTotal\tCompleted\tPending\tOverdue\tCompletion Rate"); err != nil {
+		return err
+	}
+
+	for _, stats := range byProject {
+		name := stats.Project
+		if name == "" {
+			name = "(none)"
+		}
+
+		if _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.1f%%\n", name, stats.Total, stats.Completed, stats.Pending, stats.Overdue, stats.CompletionRate()); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// TagsCommand handles the tags command.
+func TagsCommand(service *todo.Service, _ []string) error {
+	counts, err := service.GetTagCounts()
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		records := make([]record, len(counts))
+		for i, count := range counts {
+			records[i] = tagRecord(count)
+		}
+		return printRecords(records, tagRecord(todo.TagCount{}))
+	}
+
+	if len(counts) == 0 {
+		fmt.Println("No tags found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, "Tag\tOpen\tCompleted"); err != nil {
+		return err
+	}
+
+	for _, count := range counts {
+		if _, err := fmt.Fprintf(w, "%s\t%d\t%d\n", count.Tag, count.Open, count.Completed); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
 // VersionCommand handles the version command.
 func VersionCommand(_ *todo.Service, _ []string) error {
+	if structuredOutput() {
+		return printRecord(record{{"version", "1.0.0"}})
+	}
 	fmt.Printf("ToDo Manager v1.0.0\n")
 	return nil
 }
@@ -237,26 +891,189 @@ USAGE:
 
 GLOBAL OPTIONS:
     -file <filename>    Todo storage file (default: data/todos.json)
+    -store <kind:path>  Todo store: json:<file> (the default, same as -file),
+                        sqlite:<file> for a SQLite database or
+                        events:<file> for an append-only event log
+    -output <format>    Output format: table (default), json, jsonl, csv,
+                        yaml or markdown; run "todo schema" for the
+                        JSON Schema of the records
+    -lock-timeout <duration>
+                        How long to wait for another todo process to
+                        release the store (default: 5s)
 
 COMMANDS:
-    add <description>   Add a new todo
-    list [OPTIONS]      List todos
+    add [OPTIONS] <description>
+                        Add a new todo
+        -priority <level>
+                        Priority level (none, low, medium, high, urgent)
+        -due <date>     Due date (YYYY-MM-DD, "tomorrow", "next friday",
+                        "in 3 days", ...)
+        -tag <tag>      Tag to attach (repeatable); "+tag" words in the
+                        description are attached as tags too
+        -project <name> Project the todo belongs to
+        -parent <id>    Make the todo a subtask of another todo
+        -every <rule>   Repeat the todo: daily, weekday, "weekly mon",
+                        "monthly 1", yearly or an RRULE such as
+                        "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO"
+    list [OPTIONS] [FILTER]
+                        List todos, optionally matching a filter expression
         -all            Show all todos (default)
-        -completed      Show only completed todos
-        -pending        Show only pending todos
-    complete <id>       Mark a todo as completed
-    incomplete <id>     Mark a todo as not completed
-    delete <id>         Delete a todo
-    stats               Show todo statistics
+        -completed      Show only completed todos (same as status:completed)
+        -pending        Show only pending todos (same as status:pending)
+        -priority <level>
+                        Show only todos with at least this priority
+        -by-priority    Sort by priority, highest first
+        -sort <fields>  Sort by comma-separated fields: id, created,
+                        completed, due, priority, description; prefix
+                        with - for descending (e.g. -sort -priority,due)
+        -overdue        Show only overdue todos
+        -due-today      Show only todos due today
+        -due-before <date>
+                        Show only todos due before this date
+        -tag <tag>      Show only todos with all of these tags (repeatable)
+        -any-tag <tag>  Show only todos with any of these tags (repeatable)
+        -project <name> Show only todos in this project
+        -tree           Show subtasks indented under their parent
+        -ready          Show only pending todos without open blockers
+        -blocked        Show only pending todos with open blockers
+        -archived       Show archived todos instead of active ones
+        -as-of <when>   Show todos as they were at a time, date or age
+                        (e.g. "2024-05-01 15:04", yesterday, 7d); needs
+                        the events store
+        -format <template>
+                        Render each todo with a Go text/template, or
+                        @file to read the template from a file; see
+                        TEMPLATES
+    search [OPTIONS] <query>
+                        Search descriptions, notes and tags; use quotes
+                        for phrases
+        -regex          Treat the query as a regular expression
+        -no-color       Do not highlight matches
+    show <id>           Show all details of a todo, including its notes
+    note <id> <text>    Add a timestamped note to a todo
+    complete [OPTIONS] <id>...
+                        Mark todos as completed; IDs may be ranges such as
+                        7-12, and either all todos are completed or none is
+        -cascade        Also complete all open subtasks
+        -force          Complete even if subtasks or blockers are still open
+        -where <filter> Also complete the todos matching a filter expression
+    edit <id> [OPTIONS] Edit a todo; without options, opens the description
+                        in $EDITOR
+        -desc <text>    New description
+        -priority <level>
+                        New priority level
+        -due <date>     New due date, or "none" to clear it
+        -tags <a,b>     Comma-separated tags replacing the current ones
+        -project <name> New project, or "" to remove it from its project
+        -parent <id>    New parent todo, or 0 to make it a top-level todo
+    prioritize <id> <level>
+                        Set the priority of a todo
+    block <id> <blocker-id>
+                        Mark a todo as blocked by another todo
+    unblock <id> <blocker-id>
+                        Remove a blocker from a todo
+    incomplete [OPTIONS] <id>...
+                        Mark todos as not completed
+        -where <filter> Also mark the todos matching a filter expression
+    delete [OPTIONS] <id>...
+                        Move todos to the trash; their subtasks move up to
+                        their parent
+        -cascade        Also delete all subtasks
+        -where <filter> Also delete the todos matching a filter expression
+    restore <id>        Restore a todo from the trash, with the subtasks
+                        deleted along with it
+    trash list          List deleted todos
+    trash purge [OPTIONS]
+                        Permanently remove deleted todos
+        -older-than <age>
+                        Only purge todos deleted longer ago than this
+                        age (e.g. 30d, 2w, 12h)
+    undo [n]            Undo the last n changes (default 1) made by add,
+                        complete, incomplete, delete, edit, prioritize,
+                        block, unblock, note and recur stop
+    redo [n]            Redo the last n undone changes (default 1)
+    history <id>        Show every change made to a todo: when, by whom,
+                        and the old and new value of each field
+    log [OPTIONS]       Show the changes made to all todos
+        -since <when>   Only show changes since a date or within an age
+                        (e.g. 2024-01-01, yesterday, 7d, 12h)
+    recur list          List recurring series
+    recur stop <id>     Stop the recurring series of a todo
+    tags                List tags with their open and completed counts
+    project add <name>  Create a project
+    project list [-all] List active (or all) projects
+    project rename <name> <new-name>
+                        Rename a project
+    project archive <name>
+                        Archive a project
+    archive [OPTIONS]   Move completed todos out of the active file into
+                        the archive
+        -before <date>  Only archive todos completed before this date
+    compact             Fold the event log of the events store into a
+                        snapshot; earlier versions can no longer be listed
+    stats [OPTIONS]     Show todo statistics
+        -by-project     Break statistics down per project
+        -include-archive
+                        Include archived todos
+    schema              Show the JSON Schema of the structured output
     help                Show this help message
     version             Show version information
 
+FILTERS:
+    Conditions are "field op value" or a bare word matching the description,
+    combined with and, or, not and parentheses. Fields: status (pending,
+    completed, overdue, blocked, ready, recurring), priority, due, created,
+    completed, updated, tag, project, id, parent, desc, text. Operators:
+    : = != < <= > >=. Dates: YYYY-MM-DD, today, +7d, -2w, "next friday", none.
+
+TEMPLATES:
+    list -format templates receive each todo with the fields .ID,
+    .Description, .Completed, .Priority, .DueAt, .Tags, .Project, .ParentID,
+    .BlockedBy, .Recurrence, .Notes, .CreatedAt, .UpdatedAt and .CompletedAt.
+    Helpers: date, datefmt <layout>, now, color <name>, pad <width>,
+    padleft <width>, truncate <width> and join <sep>. Colors (bold, dim, red,
+    green, yellow, blue, magenta, cyan) are only written to a terminal.
+
 EXAMPLES:
     todo add "Buy groceries"
+    todo add -priority high "Fix production outage"
+    todo add -due "next friday" "Submit report"
     todo list
     todo list -pending
+    todo list 'status:pending and (tag:infra or priority>=high) and due<+7d'
+    todo add "Rotate certificates +infra +security"
+    todo list -overdue
+    todo list -tag infra -tag security
+    todo list -priority high -by-priority
+    todo list -sort due,-priority
+    todo list -output json 'status:pending'
+    todo -store sqlite:data/todos.db list
+    todo -store events:data/todos.jsonl list -as-of yesterday
+    todo stats -output yaml
+    todo list -format '{{.ID | padleft 3}} {{.Description | truncate 40}} {{date .DueAt}}'
+    todo prioritize 3 urgent
+    todo add -parent 4 "Write tests"
+    todo list -tree
+    todo add -every "weekly mon" "On-call handover"
+    todo block 5 4
+    todo list -ready
+    todo note 1 "Ask about the discount"
+    todo show 1
+    todo search '"release notes" draft'
     todo complete 1
+    todo complete 3 5 7-12
+    todo complete -where 'status:pending and tag:sprint-12'
+    todo undo
+    todo history 1
+    todo log -since 7d
+    todo trash purge -older-than 30d
+    todo archive -before -30d
+    todo list -archived 'completed>=2024-01-01'
+    todo edit 1 -desc "Buy groceries and milk" -due tomorrow
     todo delete 2
+    todo project add backend
+    todo add -project backend "Add rate limiting"
+    todo stats -by-project
     todo stats
 
 `)
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"example.com/todo/internal/todo"
+)
+
+// CompactCommand handles the compact command.
+func CompactCommand(service *todo.Service, args []string) error {
+	flagSet := flag.NewFlagSet("compact", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo compact\n")
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	compacted, err := service.Compact()
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		event := record{{"action", actionCompacted}, {"events", compacted}}
+		return printRecords([]record{event}, event)
+	}
+
+	if compacted == 0 {
+		fmt.Println("Nothing to compact")
+		return nil
+	}
+	fmt.Printf("Compacted %d event(s) into a snapshot\n", compacted)
+	return nil
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+// EditCommand handles the edit command.
+func EditCommand(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("todo ID is required")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid todo ID: %s", args[0])
+	}
+
+	flagSet := flag.NewFlagSet("edit", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo edit <id> [OPTIONS]\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Without options, the description is opened in $EDITOR.\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
+	}
+
+	description := flagSet.String("desc", "", "New description")
+	priorityName := flagSet.String("priority", "", "New priority level (none, low, medium, high, urgent)")
+	due := flagSet.String("due", "", "New due date, or \"none\" to clear it")
+	tags := flagSet.String("tags", "", "Comma-separated tags replacing the current ones")
+	project := flagSet.String("project", "", "New project, or \"\" to remove it from its project")
+	parentID := flagSet.Int("parent", 0, "New parent todo, or 0 to make it a top-level todo")
+
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	todoItem, err := service.Get(id)
+	if err != nil {
+		return err
+	}
+
+	var update todo.Update
+	changed := false
+	var parseErr error
+	flagSet.Visit(func(f *flag.Flag) {
+		if parseErr != nil {
+			return
+		}
+		changed = true
+		switch f.Name {
+		case "desc":
+			update.Description = description
+		case "priority":
+			priority, err := todo.ParsePriority(*priorityName)
+			if err != nil {
+				parseErr = err
+				return
+			}
+			update.Priority = &priority
+		case "due":
+			if strings.EqualFold(*due, "none") {
+				update.ClearDueAt = true
+				return
+			}
+			dueAt, err := todo.ParseDate(*due, time.Now())
+			if err != nil {
+				parseErr = err
+				return
+			}
+			update.DueAt = &dueAt
+		case "tags":
+			tagList := []string{}
+			for _, tag := range strings.Split(*tags, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tagList = append(tagList, tag)
+				}
+			}
+			update.Tags = &tagList
+		case "project":
+			update.Project = project
+		case "parent":
+			update.ParentID = parentID
+		}
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+
+	if !changed {
+		edited, err := editInEditor(todoItem.Description)
+		if err != nil {
+			return err
+		}
+		if edited == todoItem.Description {
+			if structuredOutput() {
+				return printTodoEvent(service, actionUnchanged, id)
+			}
+			fmt.Printf("No changes to todo #%d\n", id)
+			return nil
+		}
+		update.Description = &edited
+	}
+
+	updated, err := service.Update(id, update)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printRecords([]record{todoEvent(service, actionUpdated, *updated)}, todoEvent(service, actionUpdated, todo.Todo{}))
+	}
+	fmt.Printf("Updated todo #%d: %s\n", updated.ID, updated.Description)
+	return nil
+}
+
+// editInEditor opens text in the user's editor through a temporary file and
+// returns the edited text with surrounding whitespace trimmed. VISUAL and
+// EDITOR are skipped when they are empty or only whitespace.
+func editInEditor(text string) (string, error) {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	file, err := os.CreateTemp("", "todo-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+
+	if _, err := file.WriteString(text + "\n"); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	// The editor may include arguments, e.g. "code --wait".
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], file.Name())...) // #nosec G204 -- the editor is chosen by the user.
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read temporary file: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,17 @@
+package cli
+
+import "testing"
+
+func TestEditInEditor_BlankVisual(t *testing.T) {
+	// "true" exits without changing the file, so the text comes back as is.
+	t.Setenv("VISUAL", " \t ")
+	t.Setenv("EDITOR", "true")
+
+	edited, err := editInEditor("Buy milk")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if edited != "Buy milk" {
+		t.Errorf("Expected %q, got %q", "Buy milk", edited)
+	}
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+// stringList is a flag.Value collecting every occurrence of a repeatable flag.
+type stringList []string
+
+// String returns the collected values joined by commas.
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set appends a value.
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments, e.g. "complete 4 -force", and returns the positional arguments.
+func parseInterspersed(flagSet *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flagSet.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flagSet.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseIDPair parses two todo IDs from the first two arguments.
+func parseIDPair(args []string) (int, int, error) {
+	if len(args) < 2 {
+		return 0, 0, fmt.Errorf("two todo IDs are required")
+	}
+
+	first, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid todo ID: %s", args[0])
+	}
+
+	second, err := strconv.Atoi(args[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid todo ID: %s", args[1])
+	}
+
+	return first, second, nil
+}
+
+// maxRangeIDs bounds how many IDs a range given to selectIDs may hold.
+const maxRangeIDs = 1000
+
+// selectIDs returns the todo IDs given as arguments, each an ID or an
+// inclusive range such as 7-12 of at most maxRangeIDs IDs, followed by the
+// IDs of the todos matching the -where filter expression, if given.
+func selectIDs(service *todo.Service, args []string, where string) ([]int, error) {
+	if len(args) == 0 && where == "" {
+		return nil, fmt.Errorf("todo ID is required")
+	}
+
+	var ids []int
+	for _, arg := range args {
+		first, last, isRange := strings.Cut(arg, "-")
+		if !isRange {
+			last = first
+		}
+
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid todo ID: %s", arg)
+		}
+		to, err := strconv.Atoi(last)
+		if err != nil || to < from {
+			return nil, fmt.Errorf("invalid todo ID range: %s", arg)
+		}
+		if to-from >= maxRangeIDs {
+			return nil, fmt.Errorf("todo ID range %s is too large (at most %d IDs)", arg, maxRangeIDs)
+		}
+
+		for id := from; id <= to; id++ {
+			ids = append(ids, id)
+		}
+	}
+
+	if where != "" {
+		matches, err := service.Query(where)
+		if err != nil {
+			return nil, queryError(where, err)
+		}
+		if len(matches) == 0 && len(args) == 0 {
+			return nil, fmt.Errorf("no todos match %q", where)
+		}
+		for _, match := range matches {
+			ids = append(ids, match.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+// parseAge parses an age such as "30d", "2w" or a Go duration like "12h".
+func parseAge(text string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(text, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(text, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit != 0 {
+		n, err := strconv.Atoi(text[:len(text)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q (expected e.g. 30d, 2w or 12h)", text)
+		}
+		return time.Duration(n) * unit, nil
+	}
+
+	age, err := time.ParseDuration(text)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q (expected e.g. 30d, 2w or 12h)", text)
+	}
+	return age, nil
+}
+
+// queryError adds a pointer to the failing column of an invalid query.
+func queryError(expression string, err error) error {
+	var queryErr *todo.QueryError
+	if !errors.As(err, &queryErr) {
+		return err
+	}
+	return fmt.Errorf("%w\n  %s\n  %s^", err, expression, strings.Repeat(" ", queryErr.Column-1))
+}
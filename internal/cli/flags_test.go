@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSelectIDs(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expected  []int
+		expectErr bool
+	}{
+		{"single IDs", []string{"3", "5"}, []int{3, 5}, false},
+		{"range", []string{"7-9"}, []int{7, 8, 9}, false},
+		{"IDs and ranges", []string{"1", "4-5"}, []int{1, 4, 5}, false},
+		{"range too large", []string{"1-1001"}, nil, true},
+		{"range up to the largest int", []string{"1-9223372036854775807"}, nil, true},
+		{"reversed range", []string{"9-7"}, nil, true},
+		{"invalid ID", []string{"x"}, nil, true},
+		{"negative ID", []string{"-3"}, nil, true},
+		{"no IDs", nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := selectIDs(nil, tt.args, "")
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error, got IDs %v", ids)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+
+	ids, err := selectIDs(nil, []string{"1-1000"}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ids) != maxRangeIDs {
+		t.Errorf("Expected %d IDs, got %d", maxRangeIDs, len(ids))
+	}
+}
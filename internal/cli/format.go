@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"example.com/todo/internal/todo"
+)
+
+var colorCodes = map[string]string{
+	"bold":    "\x1b[1m",
+	"dim":     "\x1b[2m",
+	"red":     "\x1b[31m",
+	"green":   "\x1b[32m",
+	"yellow":  "\x1b[33m",
+	"blue":    "\x1b[34m",
+	"magenta": "\x1b[35m",
+	"cyan":    "\x1b[36m",
+}
+
+const colorReset = "\x1b[0m"
+
+// parseListTemplate parses a list -format template, or the contents of the
+// named file when the argument starts with "@". A single trailing newline is
+// dropped, since every todo is written on its own line.
+func parseListTemplate(format string) (*template.Template, error) {
+	text := format
+	if path, ok := strings.CutPrefix(format, "@"); ok {
+		content, err := os.ReadFile(path) // #nosec G304 -- the user chooses the template file.
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		text = string(content)
+	}
+	text = strings.TrimSuffix(text, "\n")
+
+	tmpl, err := template.New("format").Funcs(templateFuncs(useColor())).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateFuncs returns the helpers available in list -format templates.
+// Colors are dropped when color is false, so templates work unchanged in
+// pipes.
+func templateFuncs(color bool) template.FuncMap {
+	return template.FuncMap{
+		// date formats a time as YYYY-MM-DD; datefmt takes a Go layout.
+		"date": func(value any) (string, error) {
+			return formatTime(todo.DateFormat, value)
+		},
+		"datefmt": formatTime,
+		"now":     time.Now,
+		"color": func(name string, value any) (string, error) {
+			code, ok := colorCodes[name]
+			if !ok {
+				return "", fmt.Errorf("unknown color %q", name)
+			}
+			text := fmt.Sprint(value)
+			if !color {
+				return text, nil
+			}
+			return code + text + colorReset, nil
+		},
+		// pad and padleft align a value in a column of the given width.
+		"pad": func(width int, value any) string {
+			text := fmt.Sprint(value)
+			return text + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(text)))
+		},
+		"padleft": func(width int, value any) string {
+			text := fmt.Sprint(value)
+			return strings.Repeat(" ", max(0, width-utf8.RuneCountInString(text))) + text
+		},
+		"truncate": func(width int, value any) string {
+			text := []rune(fmt.Sprint(value))
+			if width < 1 || len(text) <= width {
+				return string(text)
+			}
+			return string(text[:width-1]) + "…"
+		},
+		"join": func(sep string, values []string) string {
+			return strings.Join(values, sep)
+		},
+	}
+}
+
+// formatTime formats a time.Time or *time.Time with a Go layout; a nil time
+// becomes an empty string.
+func formatTime(layout string, value any) (string, error) {
+	switch t := value.(type) {
+	case time.Time:
+		return t.Format(layout), nil
+	case *time.Time:
+		if t == nil {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("cannot format %T as a date", value)
+	}
+}
+
+// printTemplate writes every todo through the template, one per line.
+func printTemplate(tmpl *template.Template, todos []todo.Todo) error {
+	for _, todoItem := range todos {
+		if err := tmpl.Execute(os.Stdout, todoItem); err != nil {
+			return fmt.Errorf("failed to render todo #%d: %w", todoItem.ID, err)
+		}
+		fmt.Println()
+	}
+	return nil
+}
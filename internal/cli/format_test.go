@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	due := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+	var noDue *time.Time
+
+	tests := []struct {
+		name     string
+		format   string
+		data     any
+		color    bool
+		expected string
+	}{
+		{"pad", "[{{pad 5 .}}]", "ab", false, "[ab   ]"},
+		{"pad wider value", "[{{pad 2 .}}]", "abcd", false, "[abcd]"},
+		{"pad counts runes", "[{{pad 4 .}}]", "né", false, "[né  ]"},
+		{"padleft", "[{{padleft 5 .}}]", 42, false, "[   42]"},
+		{"padleft wider value", "[{{padleft 1 .}}]", 123, false, "[123]"},
+		{"truncate", "{{truncate 4 .}}", "abcdef", false, "abc…"},
+		{"truncate short value", "{{truncate 10 .}}", "abc", false, "abc"},
+		{"truncate exact width", "{{truncate 3 .}}", "abc", false, "abc"},
+		{"truncate runes", "{{truncate 3 .}}", "héllo", false, "hé…"},
+		{"truncate zero width", "{{truncate 0 .}}", "abc", false, "abc"},
+		{"date", "{{date .}}", due, false, "2024-03-05"},
+		{"date pointer", "{{date .}}", &due, false, "2024-03-05"},
+		{"date nil pointer", "[{{date .}}]", noDue, false, "[]"},
+		{"date nil", "[{{date .}}]", nil, false, "[]"},
+		{"datefmt", `{{datefmt "15:04" .}}`, due, false, "14:30"},
+		{"datefmt nil pointer", `[{{datefmt "15:04" .}}]`, noDue, false, "[]"},
+		{"color", `{{color "red" .}}`, "late", true, "\x1b[31mlate\x1b[0m"},
+		{"color disabled", `{{color "red" .}}`, "late", false, "late"},
+		{"join", `{{join ", " .}}`, []string{"a", "b"}, false, "a, b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("test").Funcs(templateFuncs(tt.color)).Parse(tt.format)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var out strings.Builder
+			if err := tmpl.Execute(&out, tt.data); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestTemplateFuncs_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   any
+	}{
+		{"date of a non-time", "{{date .}}", "2024-03-05"},
+		{"unknown color", `{{color "pink" .}}`, "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("test").Funcs(templateFuncs(false)).Parse(tt.format)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := tmpl.Execute(&strings.Builder{}, tt.data); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+// HistoryCommand handles the history command.
+func HistoryCommand(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("todo ID is required")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid todo ID: %s", args[0])
+	}
+
+	history, err := service.History(id)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printChanges(history)
+	}
+
+	if len(history) == 0 {
+		fmt.Printf("No history for todo #%d.\n", id)
+		return nil
+	}
+
+	return printChangeTable(history, false)
+}
+
+// LogCommand handles the log command.
+func LogCommand(service *todo.Service, args []string) error {
+	flagSet := flag.NewFlagSet("log", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo log [OPTIONS]\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
+	}
+
+	since := flagSet.String("since", "", "Only show changes made on or after this date, or within this age (e.g. 7d, 12h)")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	var from time.Time
+	if *since != "" {
+		var err error
+		from, err = parseSince(*since, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	log, err := service.Log(from)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printChanges(log)
+	}
+
+	if len(log) == 0 {
+		fmt.Println("No changes found.")
+		return nil
+	}
+
+	return printChangeTable(log, true)
+}
+
+// parseSince parses the -since value of log: an age such as 7d or 12h, or
+// a date as accepted by -due.
+func parseSince(text string, now time.Time) (time.Time, error) {
+	if age, err := parseAge(text); err == nil {
+		return now.Add(-age), nil
+	}
+	return todo.ParseDate(text, now)
+}
+
+// parseAsOf interprets the time given to list -as-of: an age such as 7d
+// means that long ago, a time such as "2024-05-01 15:04" that time, and a
+// date the end of that day.
+func parseAsOf(text string, now time.Time) (time.Time, error) {
+	if age, err := parseAge(text); err == nil {
+		return now.Add(-age), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if at, err := time.ParseInLocation(layout, text, now.Location()); err == nil {
+			return at, nil
+		}
+	}
+
+	date, err := todo.ParseDate(text, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+func printChanges(changes []todo.Change) error {
+	records := make([]record, len(changes))
+	for i, change := range changes {
+		records[i] = changeRecord(change)
+	}
+	return printRecords(records, changeRecord(todo.Change{}))
+}
+
+func printChangeTable(changes []todo.Change, withTodo bool) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "When\tBy\tAction\tChange"
+	if withTodo {
+		header = "When\tTodo\tBy\tAction\tChange"
+	}
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		by := change.By
+		if by == "" {
+			by = "-"
+		}
+
+		when := change.At.Local().Format("2006-01-02 15:04")
+		var err error
+		if withTodo {
+			_, err = fmt.Fprintf(w, "%s\t#%d\t%s\t%s\t%s\n", when, change.TodoID, by, change.Action, change)
+		} else {
+			_, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", when, by, change.Action, change)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,364 @@
+package cli
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"example.com/todo/internal/todo"
+)
+
+// OutputFormat selects how commands write their results.
+type OutputFormat string
+
+// Supported output formats. Table is the human-readable default; the others
+// write the records documented in schema.json.
+const (
+	OutputTable    OutputFormat = "table"
+	OutputJSON     OutputFormat = "json"
+	OutputJSONL    OutputFormat = "jsonl"
+	OutputCSV      OutputFormat = "csv"
+	OutputYAML     OutputFormat = "yaml"
+	OutputMarkdown OutputFormat = "markdown"
+)
+
+var outputFormat = OutputTable
+
+//go:embed schema.json
+var outputSchema string
+
+// SetOutputFormat selects the output format used by all commands.
+func SetOutputFormat(name string) error {
+	switch format := OutputFormat(strings.ToLower(name)); format {
+	case OutputTable, OutputJSON, OutputJSONL, OutputCSV, OutputYAML, OutputMarkdown:
+		outputFormat = format
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q (expected table, json, jsonl, csv, yaml or markdown)", name)
+	}
+}
+
+// SchemaCommand handles the schema command, printing the JSON Schema of the
+// structured output formats.
+func SchemaCommand(_ *todo.Service, _ []string) error {
+	fmt.Print(outputSchema)
+	return nil
+}
+
+// structuredOutput reports whether commands should write records instead
+// of their human-readable text.
+func structuredOutput() bool {
+	return outputFormat != OutputTable
+}
+
+// record is an object whose fields keep their order in every format.
+// Values are nil, strings, numbers, booleans, records or slices of those.
+type record []field
+
+type field struct {
+	key   string
+	value any
+}
+
+// MarshalJSON encodes the record as a JSON object in field order.
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalJSON(f.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalJSON encodes a value without escaping HTML characters, so
+// descriptions like "a < b" stay readable.
+func marshalJSON(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// printRecord writes a single result, such as the todo shown by show. JSON
+// and YAML write an object rather than a list.
+func printRecord(r record) error {
+	return writeRecords(os.Stdout, []record{r}, r, true)
+}
+
+// printRecords writes a list of results. Example is a record of the same
+// shape, such as one built from a zero todo: its fields are the CSV and
+// Markdown columns, so the header is written even when there are no records.
+func printRecords(records []record, example record) error {
+	return writeRecords(os.Stdout, records, example, false)
+}
+
+func writeRecords(w io.Writer, records []record, example record, single bool) error {
+	switch outputFormat {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if single {
+			return encoder.Encode(records[0])
+		}
+		if records == nil {
+			records = []record{}
+		}
+		return encoder.Encode(records)
+	case OutputJSONL:
+		for _, r := range records {
+			line, err := marshalJSON(r)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputCSV:
+		return writeCSV(w, records, example)
+	case OutputYAML:
+		var buf strings.Builder
+		if single {
+			writeYAMLRecord(&buf, records[0], "")
+		} else {
+			writeYAMLList(&buf, records, "")
+		}
+		_, err := io.WriteString(w, buf.String())
+		return err
+	case OutputMarkdown:
+		return writeMarkdown(w, records, example)
+	default:
+		return fmt.Errorf("output format %q does not support records", outputFormat)
+	}
+}
+
+func writeCSV(w io.Writer, records []record, example record) error {
+	writer := csv.NewWriter(w)
+	header, _ := flatten(example, "")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, r := range records {
+		_, values := flatten(r, "")
+		if err := writer.Write(values); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeMarkdown(w io.Writer, records []record, example record) error {
+	header, _ := flatten(example, "")
+	rows := [][]string{header, make([]string, len(header))}
+	for i := range header {
+		rows[1][i] = "---"
+	}
+	for _, r := range records {
+		_, values := flatten(r, "")
+		rows = append(rows, values)
+	}
+
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cell = strings.ReplaceAll(cell, "|", `\|`)
+			cells[i] = strings.ReplaceAll(cell, "\n", "<br>")
+		}
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flatten turns a record into columns for CSV and Markdown. Nested records
+// become dotted columns such as "todo.id", lists of scalars are joined with
+// commas and lists of records are written as JSON.
+func flatten(r record, prefix string) (keys, values []string) {
+	for _, f := range r {
+		if nested, ok := f.value.(record); ok {
+			nestedKeys, nestedValues := flatten(nested, prefix+f.key+".")
+			keys = append(keys, nestedKeys...)
+			values = append(values, nestedValues...)
+			continue
+		}
+		keys = append(keys, prefix+f.key)
+		values = append(values, cellText(f.value))
+	}
+	return keys, values
+}
+
+func cellText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ",")
+	case []int:
+		return joinInts(v, ",")
+	case []record:
+		if len(v) == 0 {
+			return ""
+		}
+		text, _ := marshalJSON(v)
+		return string(text)
+	default:
+		return scalarText(v)
+	}
+}
+
+func writeYAMLRecord(buf *strings.Builder, r record, indent string) {
+	if len(r) == 0 {
+		buf.WriteString(indent + "{}\n")
+		return
+	}
+	for _, f := range r {
+		buf.WriteString(indent + f.key + ":")
+		switch v := f.value.(type) {
+		case record:
+			if len(v) == 0 {
+				buf.WriteString(" {}\n")
+				continue
+			}
+			buf.WriteString("\n")
+			writeYAMLRecord(buf, v, indent+"  ")
+		case []string, []int, []record:
+			items := listItems(v)
+			if len(items) == 0 {
+				buf.WriteString(" []\n")
+				continue
+			}
+			buf.WriteString("\n")
+			writeYAMLList(buf, items, indent+"  ")
+		default:
+			buf.WriteString(" " + yamlScalar(v) + "\n")
+		}
+	}
+}
+
+func writeYAMLList[T any](buf *strings.Builder, items []T, indent string) {
+	if len(items) == 0 {
+		buf.WriteString(indent + "[]\n")
+		return
+	}
+	for _, item := range items {
+		r, ok := any(item).(record)
+		if !ok {
+			buf.WriteString(indent + "- " + yamlScalar(item) + "\n")
+			continue
+		}
+		// Render the mapping one level deeper and put the dash in place of
+		// the indentation of its first line.
+		var nested strings.Builder
+		writeYAMLRecord(&nested, r, indent+"  ")
+		buf.WriteString(indent + "- " + strings.TrimPrefix(nested.String(), indent+"  "))
+	}
+}
+
+func listItems(value any) []any {
+	var items []any
+	switch v := value.(type) {
+	case []string:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []int:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []record:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// yamlScalar writes a scalar, quoting strings that YAML would otherwise read
+// as another type or that contain special characters.
+func yamlScalar(value any) string {
+	text, ok := value.(string)
+	if !ok {
+		if value == nil {
+			return "null"
+		}
+		return scalarText(value)
+	}
+
+	if yamlPlain(text) {
+		return text
+	}
+	return strconv.Quote(text)
+}
+
+func yamlPlain(text string) bool {
+	if text == "" || strings.TrimSpace(text) != text {
+		return false
+	}
+	switch strings.ToLower(text) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return false
+	}
+	// Strings must start with a letter, so numbers and dates stay quoted.
+	for i, r := range text {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		case i > 0 && strings.ContainsRune(" _.,/()+-", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func scalarText(value any) string {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func joinInts(ids []int, sep string) string {
+	texts := make([]string, len(ids))
+	for i, id := range ids {
+		texts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(texts, sep)
+}
@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"example.com/todo/internal/todo"
+)
+
+// captureOutput returns what fn writes to stdout in the given format.
+func captureOutput(t *testing.T, format OutputFormat, fn func() error) string {
+	t.Helper()
+
+	previousFormat, previousStdout := outputFormat, os.Stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	outputFormat, os.Stdout = format, writer
+	defer func() {
+		outputFormat, os.Stdout = previousFormat, previousStdout
+	}()
+
+	fnErr := fn()
+	writer.Close()
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fnErr != nil {
+		t.Fatalf("Unexpected error: %v", fnErr)
+	}
+	return string(output)
+}
+
+func TestPrintRecords(t *testing.T) {
+	records := []record{
+		{
+			{"id", 1},
+			{"description", `Say "hi", then | go`},
+			{"done", false},
+			{"due", nil},
+			{"tags", []string{"a", "b"}},
+			{"project", record{{"name", "Home"}}},
+		},
+		{
+			{"id", 2},
+			{"description", "a < b\nnext"},
+			{"done", true},
+			{"due", "2024-03-05"},
+			{"tags", []string{}},
+			{"project", record{{"name", "yes"}}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		format   OutputFormat
+		records  []record
+		expected string
+	}{
+		{
+			name:    "json keeps field order and does not escape HTML",
+			format:  OutputJSON,
+			records: records,
+			expected: `[
+  {
+    "id": 1,
+    "description": "Say \"hi\", then | go",
+    "done": false,
+    "due": null,
+    "tags": [
+      "a",
+      "b"
+    ],
+    "project": {
+      "name": "Home"
+    }
+  },
+  {
+    "id": 2,
+    "description": "a < b\nnext",
+    "done": true,
+    "due": "2024-03-05",
+    "tags": [],
+    "project": {
+      "name": "yes"
+    }
+  }
+]
+`,
+		},
+		{
+			name:     "json empty list",
+			format:   OutputJSON,
+			expected: "[]\n",
+		},
+		{
+			name:    "jsonl writes one object per line",
+			format:  OutputJSONL,
+			records: records,
+			expected: `{"id":1,"description":"Say \"hi\", then | go","done":false,"due":null,"tags":["a","b"],"project":{"name":"Home"}}
+{"id":2,"description":"a < b\nnext","done":true,"due":"2024-03-05","tags":[],"project":{"name":"yes"}}
+`,
+		},
+		{
+			name:     "jsonl empty list",
+			format:   OutputJSONL,
+			expected: "",
+		},
+		{
+			name:    "csv quotes and flattens nested records",
+			format:  OutputCSV,
+			records: records,
+			expected: `id,description,done,due,tags,project.name
+1,"Say ""hi"", then | go",false,,"a,b",Home
+2,"a < b
+next",true,2024-03-05,,yes
+`,
+		},
+		{
+			name:     "csv empty list writes the header",
+			format:   OutputCSV,
+			expected: "id,description,done,due,tags,project.name\n",
+		},
+		{
+			name:    "yaml quotes strings that are not plain",
+			format:  OutputYAML,
+			records: records,
+			expected: `- id: 1
+  description: "Say \"hi\", then | go"
+  done: false
+  due: null
+  tags:
+    - a
+    - b
+  project:
+    name: Home
+- id: 2
+  description: "a < b\nnext"
+  done: true
+  due: "2024-03-05"
+  tags: []
+  project:
+    name: "yes"
+`,
+		},
+		{
+			name:     "yaml empty list",
+			format:   OutputYAML,
+			expected: "[]\n",
+		},
+		{
+			name:    "markdown escapes pipes and newlines",
+			format:  OutputMarkdown,
+			records: records,
+			expected: `| id | description | done | due | tags | project.name |
+| --- | --- | --- | --- | --- | --- |
+| 1 | Say "hi", then \| go | false |  | a,b | Home |
+| 2 | a < b<br>next | true | 2024-03-05 |  | yes |
+`,
+		},
+		{
+			name:   "markdown empty list writes the header",
+			format: OutputMarkdown,
+			expected: `| id | description | done | due | tags | project.name |
+| --- | --- | --- | --- | --- | --- |
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := captureOutput(t, tt.format, func() error {
+				return printRecords(tt.records, records[0])
+			})
+			if output != tt.expected {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestPrintRecord(t *testing.T) {
+	r := record{
+		{"id", 3},
+		{"subtasks", []record{{{"id", 4}, {"note", nil}}}},
+		{"dependencies", []int{}},
+		{"meta", record{}},
+	}
+
+	tests := []struct {
+		format   OutputFormat
+		expected string
+	}{
+		{OutputJSON, `{
+  "id": 3,
+  "subtasks": [
+    {
+      "id": 4,
+      "note": null
+    }
+  ],
+  "dependencies": [],
+  "meta": {}
+}
+`},
+		{OutputJSONL, `{"id":3,"subtasks":[{"id":4,"note":null}],"dependencies":[],"meta":{}}
+`},
+		{OutputCSV, `id,subtasks,dependencies
+3,"[{""id"":4,""note"":null}]",
+`},
+		{OutputYAML, `id: 3
+subtasks:
+  - id: 4
+    note: null
+dependencies: []
+meta: {}
+`},
+		{OutputMarkdown, `| id | subtasks | dependencies |
+| --- | --- | --- |
+| 3 | [{"id":4,"note":null}] |  |
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			output := captureOutput(t, tt.format, func() error {
+				return printRecord(r)
+			})
+			if output != tt.expected {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestPrintRecords_Table(t *testing.T) {
+	captureOutput(t, OutputTable, func() error {
+		if err := printRecords(nil, record{}); err == nil {
+			t.Error("Expected error for the table format, got nil")
+		}
+		return nil
+	})
+}
+
+func TestPrintRecords_EmptyEvents(t *testing.T) {
+	output := captureOutput(t, OutputCSV, func() error {
+		return printRecords(nil, journalEvent(actionUndone, todo.JournalEntry{}))
+	})
+
+	expected := "action,change.action,change.id,change.ids,change.at\n"
+	if output != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, output)
+	}
+}
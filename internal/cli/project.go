@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"example.com/todo/internal/todo"
+)
+
+// ProjectCommand handles the project command and its subcommands.
+func ProjectCommand(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("project subcommand is required (add, list, rename, archive)")
+	}
+
+	switch args[0] {
+	case "add":
+		return projectAdd(service, args[1:])
+	case "list":
+		return projectList(service, args[1:])
+	case "rename":
+		return projectRename(service, args[1:])
+	case "archive":
+		return projectArchive(service, args[1:])
+	default:
+		return fmt.Errorf("unknown project subcommand: %s", args[0])
+	}
+}
+
+func projectAdd(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("project name is required")
+	}
+
+	project, err := service.AddProject(args[0])
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printRecords([]record{projectEvent(actionProjectAdded, *project)}, projectEvent(actionProjectAdded, todo.Project{}))
+	}
+	fmt.Printf("Added project %s\n", project.Name)
+	return nil
+}
+
+func projectList(service *todo.Service, args []string) error {
+	flagSet := flag.NewFlagSet("project list", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo project list [OPTIONS]\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
+	}
+
+	showAll := flagSet.Bool("all", false, "Include archived projects")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	byProject, err := service.GetStatsByProject()
+	if err != nil {
+		return err
+	}
+	stats := make(map[string]todo.ProjectStats)
+	for _, projectStats := range byProject {
+		stats[projectStats.Project] = projectStats
+	}
+
+	var projects []todo.Project
+	for _, project := range service.GetProjects() {
+		if project.Archived && !*showAll {
+			continue
+		}
+		projects = append(projects, project)
+	}
+
+	if structuredOutput() {
+		records := make([]record, len(projects))
+		for i, project := range projects {
+			records[i] = projectRecord(project)
+		}
+		return printRecords(records, projectRecord(todo.Project{}))
+	}
+
+	if len(projects) == 0 {
+		fmt.Println("No projects found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, "Project\tStatus\tPending\tCompleted\tCreated"); err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		status := "active"
+		if project.Archived {
+			status = "archived"
+		}
+
+		created := project.CreatedAt.Format("2006-01-02 15:04")
+		projectStats := stats[project.Name]
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", project.Name, status, projectStats.Pending, projectStats.Completed, created); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func projectRename(service *todo.Service, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("current and new project names are required")
+	}
+
+	if err := service.RenameProject(args[0], args[1]); err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printProjectEvent(service, actionProjectRenamed, args[1])
+	}
+	fmt.Printf("Renamed project %s to %s\n", args[0], args[1])
+	return nil
+}
+
+func projectArchive(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("project name is required")
+	}
+
+	if err := service.ArchiveProject(args[0]); err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printProjectEvent(service, actionProjectArchived, args[0])
+	}
+	fmt.Printf("Archived project %s\n", args[0])
+	return nil
+}
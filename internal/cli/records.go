@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"math"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+// The functions below build the records written by the structured output
+// formats. Their shape is documented in schema.json and must stay stable:
+// add fields rather than renaming or removing them.
+
+// Actions reported by mutation events.
+const (
+	actionAdded             = "added"
+	actionCompleted         = "completed"
+	actionIncomplete        = "incomplete"
+	actionUpdated           = "updated"
+	actionUnchanged         = "unchanged"
+	actionDeleted           = "deleted"
+	actionBlocked           = "blocked"
+	actionUnblocked         = "unblocked"
+	actionNoted             = "noted"
+	actionRecurrenceStopped = "recurrence_stopped"
+	actionProjectAdded      = "project_added"
+	actionProjectRenamed    = "project_renamed"
+	actionProjectArchived   = "project_archived"
+	actionUndone            = "undone"
+	actionRestored          = "restored"
+	actionPurged            = "purged"
+	actionArchived          = "archived"
+	actionRedone            = "redone"
+	actionCompacted         = "compacted"
+)
+
+func todoRecord(service *todo.Service, t todo.Todo) record {
+	notes := make([]record, len(t.Notes))
+	for i, note := range t.Notes {
+		notes[i] = record{
+			{"text", note.Text},
+			{"created_at", timestamp(note.CreatedAt)},
+		}
+	}
+
+	var due, recurrence any
+	if t.DueAt != nil {
+		due = t.DueAt.Format(todo.DateFormat)
+	}
+	if t.Recurrence != nil {
+		recurrence = t.Recurrence.String()
+	}
+
+	tags := t.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	blockedBy := t.BlockedBy
+	if blockedBy == nil {
+		blockedBy = []int{}
+	}
+	// A record cannot fail, so blockers that cannot be read count as
+	// closed.
+	blockers, _ := service.OpenBlockers(t)
+
+	return record{
+		{"id", t.ID},
+		{"description", t.Description},
+		{"completed", t.Completed},
+		{"priority", t.Priority.String()},
+		{"due", due},
+		{"overdue", t.IsOverdue(time.Now())},
+		{"project", optionalString(t.Project)},
+		{"tags", tags},
+		{"parent_id", optionalID(t.ParentID)},
+		{"blocked_by", blockedBy},
+		{"blocked", len(blockers) > 0},
+		{"recurrence", recurrence},
+		{"series_id", optionalID(t.SeriesID)},
+		{"notes", notes},
+		{"created_at", timestamp(t.CreatedAt)},
+		{"updated_at", optionalTimestamp(t.UpdatedAt)},
+		{"completed_at", optionalTimestamp(t.CompletedAt)},
+		{"deleted_at", optionalTimestamp(t.DeletedAt)},
+	}
+}
+
+func todoRecords(service *todo.Service, todos []todo.Todo) []record {
+	records := make([]record, len(todos))
+	for i, t := range todos {
+		records[i] = todoRecord(service, t)
+	}
+	return records
+}
+
+// todoEvent reports a change made to a todo by a mutation command.
+func todoEvent(service *todo.Service, action string, t todo.Todo) record {
+	return record{
+		{"action", action},
+		{"todo", todoRecord(service, t)},
+	}
+}
+
+// printTodoEvent writes a single event for the todo with the given ID.
+func printTodoEvent(service *todo.Service, action string, id int) error {
+	todoItem, err := service.Get(id)
+	if err != nil {
+		return err
+	}
+	return printRecords([]record{todoEvent(service, action, todoItem)}, todoEvent(service, action, todo.Todo{}))
+}
+
+// printProjectEvent writes a single event for the project with the given
+// name.
+func printProjectEvent(service *todo.Service, action string, name string) error {
+	project, err := service.GetProject(name)
+	if err != nil {
+		return err
+	}
+	return printRecords([]record{projectEvent(action, *project)}, projectEvent(action, todo.Project{}))
+}
+
+// journalEvent reports a change that was undone or redone.
+func journalEvent(action string, entry todo.JournalEntry) record {
+	ids := entry.IDs
+	if len(ids) == 0 {
+		ids = []int{entry.ID}
+	}
+	return record{
+		{"action", action},
+		{"change", record{
+			{"action", entry.Action},
+			{"id", entry.ID},
+			{"ids", ids},
+			{"at", timestamp(entry.At)},
+		}},
+	}
+}
+
+func projectRecord(project todo.Project) record {
+	return record{
+		{"name", project.Name},
+		{"archived", project.Archived},
+		{"created_at", timestamp(project.CreatedAt)},
+		{"archived_at", optionalTimestamp(project.ArchivedAt)},
+	}
+}
+
+// projectEvent reports a change made to a project by a project subcommand.
+func projectEvent(action string, project todo.Project) record {
+	return record{
+		{"action", action},
+		{"project", projectRecord(project)},
+	}
+}
+
+func statsRecord(stats todo.Stats) record {
+	return record{
+		{"total", stats.Total},
+		{"completed", stats.Completed},
+		{"pending", stats.Pending},
+		{"overdue", stats.Overdue},
+		{"completion_rate", math.Round(stats.CompletionRate()*10) / 10},
+	}
+}
+
+func projectStatsRecord(stats todo.ProjectStats) record {
+	return append(record{{"project", optionalString(stats.Project)}}, statsRecord(stats.Stats)...)
+}
+
+func tagRecord(count todo.TagCount) record {
+	return record{
+		{"tag", count.Tag},
+		{"open", count.Open},
+		{"completed", count.Completed},
+	}
+}
+
+// changeRecord is one entry of the history written by history and log.
+func changeRecord(change todo.Change) record {
+	return record{
+		{"todo_id", change.TodoID},
+		{"action", change.Action},
+		{"field", change.Field},
+		{"old", optionalString(change.Old)},
+		{"new", optionalString(change.New)},
+		{"by", optionalString(change.By)},
+		{"at", timestamp(change.At)},
+	}
+}
+
+func timestamp(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+func optionalTimestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return timestamp(*t)
+}
+
+func optionalString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func optionalID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"example.com/todo/internal/todo"
+)
+
+// RecurCommand handles the recur command and its subcommands.
+func RecurCommand(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("recur subcommand is required (list, stop)")
+	}
+
+	switch args[0] {
+	case "list":
+		return recurList(service)
+	case "stop":
+		return recurStop(service, args[1:])
+	default:
+		return fmt.Errorf("unknown recur subcommand: %s", args[0])
+	}
+}
+
+func recurList(service *todo.Service) error {
+	series, err := service.GetRecurringSeries()
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return printRecords(todoRecords(service, series), todoRecord(service, todo.Todo{}))
+	}
+
+	if len(series) == 0 {
+		fmt.Println("No recurring todos found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, "Series\tNext ID\tNext Due\tSchedule\tDescription"); err != nil {
+		return err
+	}
+
+	for _, todoItem := range series {
+		dueDate := "-"
+		if todoItem.DueAt != nil {
+			dueDate = todoItem.DueAt.Format(todo.DateFormat)
+		}
+
+		if _, err := fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", todoItem.SeriesID, todoItem.ID, dueDate, todoItem.Recurrence, todoItem.Description); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func recurStop(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("todo ID is required")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid todo ID: %s", args[0])
+	}
+
+	if err := service.StopRecurrence(id); err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printTodoEvent(service, actionRecurrenceStopped, id)
+	}
+
+	fmt.Printf("Stopped the recurring series of todo #%d\n", id)
+	return nil
+}
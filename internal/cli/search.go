@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"example.com/todo/internal/todo"
+)
+
+const (
+	highlightStart = "\x1b[1;33m"
+	highlightEnd   = "\x1b[0m"
+)
+
+// SearchCommand handles the search command.
+func SearchCommand(service *todo.Service, args []string) error {
+	flagSet := flag.NewFlagSet("search", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo search [OPTIONS] <query>\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
+	}
+
+	regex := flagSet.Bool("regex", false, "Treat the query as a regular expression")
+	noColor := flagSet.Bool("no-color", false, "Do not highlight matches")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	if flagSet.NArg() == 0 {
+		return fmt.Errorf("search query is required")
+	}
+
+	results, err := service.Search(strings.Join(flagSet.Args(), " "), todo.SearchOptions{Regex: *regex})
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		records := make([]record, len(results))
+		for i, result := range results {
+			records[i] = todoRecord(service, result.Todo)
+		}
+		return printRecords(records, todoRecord(service, todo.Todo{}))
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No todos found.")
+		return nil
+	}
+
+	color := !*noColor && useColor()
+	for _, result := range results {
+		status := "[ ]"
+		if result.Todo.Completed {
+			status = "[✓]"
+		}
+
+		description := result.Todo.Description
+		var details []string
+		for _, match := range result.Matches {
+			text := highlight(match.Text, match.Ranges, color)
+			switch match.Field {
+			case todo.FieldDescription:
+				description = text
+			case todo.FieldNote:
+				note := result.Todo.Notes[match.Index]
+				details = append(details, fmt.Sprintf("note [%s]: %s", note.CreatedAt.Format("2006-01-02 15:04"), text))
+			case todo.FieldTag:
+				details = append(details, "tag: +"+text)
+			}
+		}
+
+		fmt.Printf("#%d %s %s\n", result.Todo.ID, status, description)
+		for _, detail := range details {
+			fmt.Printf("    %s\n", detail)
+		}
+	}
+
+	return nil
+}
+
+// highlight wraps the given byte ranges of text in terminal color codes.
+func highlight(text string, ranges [][2]int, color bool) string {
+	if !color || len(ranges) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, r := range ranges {
+		b.WriteString(text[last:r[0]])
+		b.WriteString(highlightStart)
+		b.WriteString(text[r[0]:r[1]])
+		b.WriteString(highlightEnd)
+		last = r[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// useColor reports whether stdout is a terminal and NO_COLOR is not set.
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
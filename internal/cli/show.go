@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+// NoteCommand handles the note command.
+func NoteCommand(service *todo.Service, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("todo ID and note text are required")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid todo ID: %s", args[0])
+	}
+
+	if _, err := service.AddNote(id, strings.Join(args[1:], " ")); err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printTodoEvent(service, actionNoted, id)
+	}
+
+	fmt.Printf("Added note to todo #%d\n", id)
+	return nil
+}
+
+// ShowCommand handles the show command.
+func ShowCommand(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("todo ID is required")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid todo ID: %s", args[0])
+	}
+
+	todoItem, err := service.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printRecord(todoRecord(service, todoItem))
+	}
+
+	const timeFormat = "2006-01-02 15:04"
+	blockers, err := service.OpenBlockers(todoItem)
+	if err != nil {
+		return err
+	}
+	status := "pending"
+	if todoItem.Completed {
+		status = "completed"
+	} else if len(blockers) > 0 {
+		status = "blocked"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fields := [][2]string{
+		{"ID", strconv.Itoa(todoItem.ID)},
+		{"Description", todoItem.Description},
+		{"Status", status},
+		{"Priority", todoItem.Priority.String()},
+	}
+	if todoItem.DueAt != nil {
+		due := todoItem.DueAt.Format(todo.DateFormat)
+		if todoItem.IsOverdue(time.Now()) {
+			due += " (overdue)"
+		}
+		fields = append(fields, [2]string{"Due", due})
+	}
+	if todoItem.Project != "" {
+		fields = append(fields, [2]string{"Project", todoItem.Project})
+	}
+	if len(todoItem.Tags) > 0 {
+		fields = append(fields, [2]string{"Tags", strings.Join(todoItem.Tags, ", ")})
+	}
+	if todoItem.ParentID != 0 {
+		fields = append(fields, [2]string{"Parent", "#" + strconv.Itoa(todoItem.ParentID)})
+	}
+	children, err := service.GetChildren(todoItem.ID)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		ids := make([]int, len(children))
+		for i, child := range children {
+			ids[i] = child.ID
+		}
+		fields = append(fields, [2]string{"Subtasks", todo.JoinIDs(ids, ", ")})
+	}
+	if len(todoItem.BlockedBy) > 0 {
+		fields = append(fields, [2]string{"Blocked By", todo.JoinIDs(todoItem.BlockedBy, ", ")})
+	}
+	if todoItem.Recurrence != nil {
+		fields = append(fields, [2]string{"Recurrence", todoItem.Recurrence.String()})
+	}
+	if todoItem.SeriesID != 0 {
+		fields = append(fields, [2]string{"Series", strconv.Itoa(todoItem.SeriesID)})
+	}
+	fields = append(fields, [2]string{"Created", todoItem.CreatedAt.Format(timeFormat)})
+	if todoItem.UpdatedAt != nil {
+		fields = append(fields, [2]string{"Updated", todoItem.UpdatedAt.Format(timeFormat)})
+	}
+	if todoItem.CompletedAt != nil {
+		fields = append(fields, [2]string{"Completed", todoItem.CompletedAt.Format(timeFormat)})
+	}
+
+	for _, field := range fields {
+		if _, err := fmt.Fprintf(w, "%s:\t%s\n", field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(todoItem.Notes) > 0 {
+		fmt.Printf("\nNotes:\n")
+		for _, note := range todoItem.Notes {
+			fmt.Printf("  [%s] %s\n", note.CreatedAt.Format(timeFormat), note.Text)
+		}
+	}
+
+	return nil
+}
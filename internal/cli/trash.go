@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+// TrashCommand handles the trash command and its subcommands.
+func TrashCommand(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("trash subcommand is required (list, purge)")
+	}
+
+	switch args[0] {
+	case "list":
+		return trashList(service)
+	case "purge":
+		return trashPurge(service, args[1:])
+	default:
+		return fmt.Errorf("unknown trash subcommand: %s", args[0])
+	}
+}
+
+// RestoreCommand handles the restore command.
+func RestoreCommand(service *todo.Service, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("todo ID is required")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid todo ID: %s", args[0])
+	}
+
+	restored, err := service.Restore(id)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		events := make([]record, len(restored))
+		for i, todoItem := range restored {
+			events[i] = todoEvent(service, actionRestored, todoItem)
+		}
+		return printRecords(events, todoEvent(service, actionRestored, todo.Todo{}))
+	}
+
+	for _, todoItem := range restored {
+		fmt.Printf("Restored todo #%d: %s\n", todoItem.ID, todoItem.Description)
+	}
+	return nil
+}
+
+func trashList(service *todo.Service) error {
+	trash, err := service.GetTrash()
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return printRecords(todoRecords(service, trash), todoRecord(service, todo.Todo{}))
+	}
+
+	if len(trash) == 0 {
+		fmt.Println("The trash is empty.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, "ID\tStatus\tDescription\tDeleted"); err != nil {
+		return err
+	}
+
+	for _, todoItem := range trash {
+		status := "[ ]"
+		if todoItem.Completed {
+			status = "[✓]"
+		}
+
+		deleted := todoItem.DeletedAt.Format("2006-01-02 15:04")
+		if _, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", todoItem.ID, status, todoItem.Description, deleted); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func trashPurge(service *todo.Service, args []string) error {
+	flagSet := flag.NewFlagSet("trash purge", flag.ExitOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage: todo trash purge [OPTIONS]\n")
+		_, _ = fmt.Fprintf(flagSet.Output(), "Options:\n")
+		flagSet.PrintDefaults()
+	}
+
+	olderThan := flagSet.String("older-than", "", "Only purge todos deleted longer ago than this age (e.g. 30d, 2w, 12h)")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	before := time.Now()
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return err
+		}
+		before = before.Add(-age)
+	}
+
+	purged, err := service.PurgeTrash(before)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		events := make([]record, len(purged))
+		for i, todoItem := range purged {
+			events[i] = todoEvent(service, actionPurged, todoItem)
+		}
+		return printRecords(events, todoEvent(service, actionPurged, todo.Todo{}))
+	}
+
+	fmt.Printf("Purged %d todo(s) from the trash\n", len(purged))
+	return nil
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"example.com/todo/internal/todo"
+)
+
+// UndoCommand handles the undo command.
+func UndoCommand(service *todo.Service, args []string) error {
+	n, err := parseChangeCount(args)
+	if err != nil {
+		return err
+	}
+
+	entries, err := service.Undo(n)
+	if err != nil {
+		return err
+	}
+
+	return printJournalEntries(actionUndone, "Undid", entries)
+}
+
+// RedoCommand handles the redo command.
+func RedoCommand(service *todo.Service, args []string) error {
+	n, err := parseChangeCount(args)
+	if err != nil {
+		return err
+	}
+
+	entries, err := service.Redo(n)
+	if err != nil {
+		return err
+	}
+
+	return printJournalEntries(actionRedone, "Redid", entries)
+}
+
+// parseChangeCount parses the optional number of changes to undo or redo.
+func parseChangeCount(args []string) (int, error) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid number of changes: %s", args[0])
+	}
+	return n, nil
+}
+
+func printJournalEntries(action, verb string, entries []todo.JournalEntry) error {
+	if structuredOutput() {
+		records := make([]record, len(entries))
+		for i, entry := range entries {
+			records[i] = journalEvent(action, entry)
+		}
+		return printRecords(records, journalEvent(action, todo.JournalEntry{}))
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("%s %s (%s)\n", verb, entry, entry.At.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
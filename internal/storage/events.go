@@ -0,0 +1,617 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+// snapshotInterval is how many events are appended to the event log between
+// snapshots of the todos.
+const snapshotInterval = 200
+
+// Types of the events in the event log.
+const (
+	eventSnapshot      = "Snapshot"
+	eventTodoAdded     = "TodoAdded"
+	eventTodoUpdated   = "TodoUpdated"
+	eventTodoCompleted = "TodoCompleted"
+	eventTodoReopened  = "TodoReopened"
+	eventTodoDeleted   = "TodoDeleted"
+	eventTodoRestored  = "TodoRestored"
+	eventTodoRemoved   = "TodoRemoved"
+)
+
+// event is a line of the event log. Events about a todo carry it as it is
+// after the event, except TodoRemoved, and a Snapshot carries all todos.
+type event struct {
+	Type  string      `json:"type"`
+	At    time.Time   `json:"at"`
+	ID    int         `json:"id,omitempty"`
+	Todo  *todo.Todo  `json:"todo,omitempty"`
+	Todos []todo.Todo `json:"todos,omitempty"`
+}
+
+// eventSnapshotFile is the snapshot file: the todos as of the first Offset
+// bytes of the event log whose first event was at Base.
+type eventSnapshotFile struct {
+	Base   time.Time   `json:"base"`
+	Offset int64       `json:"offset"`
+	Todos  []todo.Todo `json:"todos"`
+}
+
+// EventLogRepository implements todo.BatchStore with an append-only JSON
+// Lines log of events, e.g. data/todos.jsonl. Every write appends an event,
+// such as TodoAdded, TodoCompleted or TodoDeleted, so the todos are rebuilt
+// by replaying the log and can be listed as they were at any earlier time.
+// Every snapshotInterval events the todos are also written to a snapshot
+// file, e.g. data/todos.jsonl.snapshot.json, from which loading replays
+// only the later events, and Compact replaces the log by a single Snapshot
+// event.
+//
+// Projects, the journal, the archive and the history are kept in files next
+// to the log, as with JSONRepository.
+type EventLogRepository struct {
+	filename string
+	files    *JSONRepository
+	// warnings receives messages about recovered problems, such as a last
+	// event cut off by a crash.
+	warnings io.Writer
+	// todos holds the todos as of the first end bytes of the log, whose
+	// first event was at base, once tracked is set. The log was size bytes
+	// long when last read or written; a torn last line, left by a crash
+	// while appending, lies between end and size and is cut off by the next
+	// write.
+	todos   map[int]todo.Todo
+	base    time.Time
+	end     int64
+	size    int64
+	tracked bool
+	// unsnapshotted counts the events appended since the last snapshot.
+	unsnapshotted int
+	// pending holds the todos with the events of the running batch
+	// applied, and events those events, while inBatch is set.
+	pending map[int]todo.Todo
+	events  []event
+	inBatch bool
+}
+
+// NewEventLogRepository creates a repository on the event log in filename.
+func NewEventLogRepository(filename string) *EventLogRepository {
+	// The files next to the log are named after all of its name, e.g.
+	// data/todos.jsonl.projects.json, so that they are not shared with a
+	// JSON store such as data/todos.json.
+	files := NewJSONRepository(filename)
+	files.sidecarBase = filename
+	return &EventLogRepository{
+		filename: filename,
+		files:    files,
+		warnings: os.Stderr,
+	}
+}
+
+// Lock locks the event log; see JSONRepository.Lock.
+func (r *EventLogRepository) Lock(timeout time.Duration) (func() error, error) {
+	return r.files.Lock(timeout)
+}
+
+// Create appends a TodoAdded event.
+func (r *EventLogRepository) Create(t todo.Todo) error {
+	return r.write(func(todos map[int]todo.Todo) (event, error) {
+		if _, ok := todos[t.ID]; ok {
+			return event{}, fmt.Errorf("todo with ID %d already exists", t.ID)
+		}
+		return event{Type: eventTodoAdded, ID: t.ID, Todo: &t}, nil
+	})
+}
+
+// Update appends an event for the change made to a todo: TodoCompleted,
+// TodoReopened, TodoDeleted, TodoRestored or else TodoUpdated.
+func (r *EventLogRepository) Update(t todo.Todo) error {
+	return r.write(func(todos map[int]todo.Todo) (event, error) {
+		old, ok := todos[t.ID]
+		if !ok {
+			return event{}, fmt.Errorf("%w: #%d", todo.ErrNotFound, t.ID)
+		}
+		return event{Type: updateEventType(old, t), ID: t.ID, Todo: &t}, nil
+	})
+}
+
+// Delete appends a TodoRemoved event, as when a todo is purged from the
+// trash or archived.
+func (r *EventLogRepository) Delete(id int) error {
+	return r.write(func(todos map[int]todo.Todo) (event, error) {
+		if _, ok := todos[id]; !ok {
+			return event{}, fmt.Errorf("%w: #%d", todo.ErrNotFound, id)
+		}
+		return event{Type: eventTodoRemoved, ID: id}, nil
+	})
+}
+
+// Get returns a todo.
+func (r *EventLogRepository) Get(id int) (todo.Todo, error) {
+	todos, err := r.current()
+	if err != nil {
+		return todo.Todo{}, err
+	}
+	t, ok := todos[id]
+	if !ok {
+		return todo.Todo{}, fmt.Errorf("%w: #%d", todo.ErrNotFound, id)
+	}
+	return cloneTodo(t), nil
+}
+
+// List returns the todos selected by opts.
+func (r *EventLogRepository) List(opts todo.ListOptions) ([]todo.Todo, error) {
+	todos, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	return opts.Apply(sortedTodos(todos))
+}
+
+// Batch runs fn and appends the events of the writes it made at once when
+// it succeeds. If events were appended by others since the log was last
+// read or written, or the files next to it were changed, Batch returns an
+// error wrapping todo.ErrConflict instead.
+func (r *EventLogRepository) Batch(fn func() error) error {
+	if r.inBatch {
+		return fn()
+	}
+	if !r.tracked {
+		if err := r.refresh(); err != nil {
+			return err
+		}
+	}
+
+	r.pending, r.events, r.inBatch = maps.Clone(r.todos), nil, true
+	err := fn()
+	pending, events := r.pending, r.events
+	r.pending, r.events, r.inBatch = nil, nil, false
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	// The files next to the log are checked too, so that changes made to
+	// them by others are conflicts as well.
+	if err := r.files.checkRevisions(); err != nil {
+		return fmt.Errorf("failed to save todos: %w", err)
+	}
+	if err := r.appendEvents(events); err != nil {
+		return fmt.Errorf("failed to save todos: %w", err)
+	}
+	r.todos = pending
+
+	r.unsnapshotted += len(events)
+	if r.unsnapshotted >= snapshotInterval {
+		// The log stays complete without the snapshot, so failing to
+		// write it only slows down loading.
+		if err := r.writeSnapshot(); err != nil {
+			_, _ = fmt.Fprintf(r.warnings, "Warning: could not write snapshot: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// LoadAsOf replays the event log up to the given time and returns the todos
+// as they were then, including the trash. Times before the first event of a
+// compacted log cannot be replayed.
+func (r *EventLogRepository) LoadAsOf(at time.Time) ([]todo.Todo, error) {
+	events, _, err := r.readEvents(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load todos: %w", err)
+	}
+
+	todos := make(map[int]todo.Todo)
+	for i, e := range events {
+		if e.At.After(at) {
+			if i == 0 && e.Type == eventSnapshot {
+				return nil, fmt.Errorf("the event log was compacted at %s; earlier states are not kept",
+					e.At.Format("2006-01-02 15:04"))
+			}
+			break
+		}
+		applyEvent(todos, e)
+	}
+	return sortedTodos(todos), nil
+}
+
+// Compact replaces the event log by a single Snapshot event holding the
+// current todos and returns how many events it replaced. The todos can no
+// longer be listed as they were before.
+func (r *EventLogRepository) Compact() (int, error) {
+	events, _, err := r.readEvents(0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compact event log: %w", err)
+	}
+	if len(events) == 0 || (len(events) == 1 && events[0].Type == eventSnapshot) {
+		return 0, nil
+	}
+
+	todos := make(map[int]todo.Todo)
+	for _, e := range events {
+		applyEvent(todos, e)
+	}
+	snapshot := event{Type: eventSnapshot, At: time.Now(), Todos: sortedTodos(todos)}
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compact event log: %w", err)
+	}
+	line = append(line, '\n')
+
+	if err := writeFile(r.filename, line); err != nil {
+		return 0, fmt.Errorf("failed to compact event log: %w", err)
+	}
+	if err := os.Remove(r.snapshotFilename()); err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to compact event log: %w", err)
+	}
+
+	r.todos, r.base, r.tracked = todos, snapshot.At, true
+	r.end, r.size = int64(len(line)), int64(len(line))
+	r.unsnapshotted = 0
+	return len(events), nil
+}
+
+// SaveProjects writes projects next to the event log.
+func (r *EventLogRepository) SaveProjects(projects []todo.Project) error {
+	if err := r.checkLog(); err != nil {
+		return fmt.Errorf("failed to save projects: %w", err)
+	}
+	return r.files.SaveProjects(projects)
+}
+
+// LoadProjects reads projects from next to the event log.
+func (r *EventLogRepository) LoadProjects() ([]todo.Project, error) {
+	return r.files.LoadProjects()
+}
+
+// SaveJournal writes the undo journal next to the event log.
+func (r *EventLogRepository) SaveJournal(journal todo.Journal) error {
+	if err := r.checkLog(); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
+	return r.files.SaveJournal(journal)
+}
+
+// LoadJournal reads the undo journal from next to the event log.
+func (r *EventLogRepository) LoadJournal() (todo.Journal, error) {
+	return r.files.LoadJournal()
+}
+
+// SaveArchive writes archived todos next to the event log.
+func (r *EventLogRepository) SaveArchive(todos []todo.Todo) error {
+	if err := r.checkLog(); err != nil {
+		return fmt.Errorf("failed to save archive: %w", err)
+	}
+	return r.files.SaveArchive(todos)
+}
+
+// LoadArchive reads archived todos from next to the event log.
+func (r *EventLogRepository) LoadArchive() ([]todo.Todo, error) {
+	return r.files.LoadArchive()
+}
+
+// SaveLastID writes the highest todo ID given next to the event log.
+func (r *EventLogRepository) SaveLastID(id int) error {
+	if err := r.checkLog(); err != nil {
+		return fmt.Errorf("failed to save last ID: %w", err)
+	}
+	return r.files.SaveLastID(id)
+}
+
+// LoadLastID reads the highest todo ID given from next to the event log.
+func (r *EventLogRepository) LoadLastID() (int, error) {
+	return r.files.LoadLastID()
+}
+
+// AppendHistory adds changes to the history file next to the event log.
+func (r *EventLogRepository) AppendHistory(changes []todo.Change) error {
+	return r.files.AppendHistory(changes)
+}
+
+// LoadHistory reads the history file next to the event log.
+func (r *EventLogRepository) LoadHistory() ([]todo.Change, error) {
+	return r.files.LoadHistory()
+}
+
+// write makes an event for the todos as they are and applies it, in the
+// running batch or else in a batch of its own.
+func (r *EventLogRepository) write(makeEvent func(todos map[int]todo.Todo) (event, error)) error {
+	if !r.inBatch {
+		return r.Batch(func() error {
+			return r.write(makeEvent)
+		})
+	}
+
+	e, err := makeEvent(r.pending)
+	if err != nil {
+		return err
+	}
+	e.At = time.Now()
+	if e.Todo != nil {
+		cloned := cloneTodo(*e.Todo)
+		e.Todo = &cloned
+	}
+	applyEvent(r.pending, e)
+	r.events = append(r.events, e)
+	return nil
+}
+
+// current returns the todos, with the events of the running batch applied
+// or else as the log holds them now.
+func (r *EventLogRepository) current() (map[int]todo.Todo, error) {
+	if r.inBatch {
+		return r.pending, nil
+	}
+	if err := r.refresh(); err != nil {
+		return nil, err
+	}
+	return r.todos, nil
+}
+
+// refresh brings the todos up to date with the event log, replaying only
+// the events appended since it was last read unless it was compacted.
+func (r *EventLogRepository) refresh() error {
+	base, err := r.readBase()
+	if err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+
+	// The size is read before the events, so that events appended in
+	// between make the next append a conflict rather than being cut off.
+	size, err := fileSize(r.filename)
+	if err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+
+	if !r.tracked || !base.Equal(r.base) || size < r.end {
+		r.todos, r.end, r.unsnapshotted = make(map[int]todo.Todo), 0, 0
+		if snapshot, ok := r.readSnapshot(base, size); ok {
+			r.todos, r.end = todoMap(snapshot.Todos), snapshot.Offset
+		}
+	}
+
+	events, end, err := r.readEvents(r.end)
+	if err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+	for _, e := range events {
+		applyEvent(r.todos, e)
+	}
+
+	r.base, r.end, r.size, r.tracked = base, end, size, true
+	r.unsnapshotted += len(events)
+	return nil
+}
+
+// checkLog returns todo.ErrConflict if the log changed since it was last
+// read or written, so that the files next to it are not saved over changes
+// made by others.
+func (r *EventLogRepository) checkLog() error {
+	if !r.tracked {
+		return nil
+	}
+	size, err := fileSize(r.filename)
+	if err != nil {
+		return err
+	}
+	base, err := r.readBase()
+	if err != nil {
+		return err
+	}
+	if size != r.size || !base.Equal(r.base) {
+		return todo.ErrConflict
+	}
+	return nil
+}
+
+// readEvents reads the events in the log from offset on and returns them
+// with the offset after the last complete one. A last line that is cut off
+// is skipped with a warning.
+func (r *EventLogRepository) readEvents(offset int64) ([]event, int64, error) {
+	file, err := os.Open(r.filename)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var events []event
+	reader := bufio.NewReader(file)
+	end := offset
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				_, _ = fmt.Fprintf(r.warnings, "Warning: ignoring the incomplete last event in %s\n", r.filename)
+			}
+			return events, end, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			var e event
+			if err := json.Unmarshal(line, &e); err != nil {
+				return nil, 0, fmt.Errorf("invalid event at offset %d: %w", end, err)
+			}
+			events = append(events, e)
+		}
+		end += int64(len(line))
+	}
+}
+
+// readBase returns the time of the first event in the log, or the zero
+// time if it is empty.
+func (r *EventLogRepository) readBase() (time.Time, error) {
+	file, err := os.Open(r.filename)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil {
+		// An empty log or a single torn line has no events.
+		return time.Time{}, nil
+	}
+	var first event
+	if err := json.Unmarshal(line, &first); err != nil {
+		return time.Time{}, fmt.Errorf("invalid event at offset 0: %w", err)
+	}
+	return first.At, nil
+}
+
+// appendEvents appends events to the log, first cutting off a torn last
+// line. It returns an error wrapping todo.ErrConflict if the log changed
+// since it was last read or written.
+func (r *EventLogRepository) appendEvents(events []event) error {
+	var data []byte
+	for _, e := range events {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	file, err := os.OpenFile(r.filename, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	// A compacted log is detected by its first event.
+	base, err := r.readBase()
+	if err != nil {
+		return err
+	}
+	if info.Size() != r.size || !base.Equal(r.base) {
+		return todo.ErrConflict
+	}
+	if r.end < r.size {
+		if err := file.Truncate(r.end); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+
+	if _, err := file.WriteAt(data, r.end); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if r.end == 0 {
+		r.base = events[0].At
+	}
+	r.end += int64(len(data))
+	r.size = r.end
+	return nil
+}
+
+// readSnapshot reads the snapshot file if it was written for the log whose
+// first event was at base and which is now size bytes long.
+func (r *EventLogRepository) readSnapshot(base time.Time, size int64) (eventSnapshotFile, bool) {
+	var snapshot eventSnapshotFile
+	if err := readJSON(r.snapshotFilename(), &snapshot); err != nil {
+		_, _ = fmt.Fprintf(r.warnings, "Warning: ignoring snapshot %s: %v\n", r.snapshotFilename(), err)
+		return eventSnapshotFile{}, false
+	}
+	if snapshot.Offset == 0 || snapshot.Offset > size || !snapshot.Base.Equal(base) {
+		return eventSnapshotFile{}, false
+	}
+	return snapshot, true
+}
+
+// writeSnapshot writes the todos as of the end of the log to the snapshot
+// file.
+func (r *EventLogRepository) writeSnapshot() error {
+	snapshot := eventSnapshotFile{Base: r.base, Offset: r.end, Todos: sortedTodos(r.todos)}
+	if err := writeJSON(r.snapshotFilename(), snapshot); err != nil {
+		return err
+	}
+	r.unsnapshotted = 0
+	return nil
+}
+
+// snapshotFilename returns the name of the snapshot file, e.g.
+// data/todos.jsonl.snapshot.json for data/todos.jsonl.
+func (r *EventLogRepository) snapshotFilename() string {
+	return r.files.sidecarFilename("snapshot")
+}
+
+// updateEventType names the change from old to t.
+func updateEventType(old, t todo.Todo) string {
+	switch {
+	case old.DeletedAt == nil && t.DeletedAt != nil:
+		return eventTodoDeleted
+	case old.DeletedAt != nil && t.DeletedAt == nil:
+		return eventTodoRestored
+	case !old.Completed && t.Completed:
+		return eventTodoCompleted
+	case old.Completed && !t.Completed:
+		return eventTodoReopened
+	}
+	return eventTodoUpdated
+}
+
+// applyEvent applies an event to the todos.
+func applyEvent(todos map[int]todo.Todo, e event) {
+	switch {
+	case e.Type == eventSnapshot:
+		clear(todos)
+		maps.Copy(todos, todoMap(e.Todos))
+	case e.Type == eventTodoRemoved:
+		delete(todos, e.ID)
+	case e.Todo != nil:
+		todos[e.ID] = *e.Todo
+	}
+}
+
+// sortedTodos returns the todos ordered by ID.
+func sortedTodos(todos map[int]todo.Todo) []todo.Todo {
+	sorted := make([]todo.Todo, 0, len(todos))
+	for _, id := range slices.Sorted(maps.Keys(todos)) {
+		sorted = append(sorted, cloneTodo(todos[id]))
+	}
+	return sorted
+}
+
+// fileSize returns the size of a file, or 0 if it does not exist.
+func fileSize(filename string) (int64, error) {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	return info.Size(), nil
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+func newTestEventLogRepository(filename string) *EventLogRepository {
+	repo := NewEventLogRepository(filename)
+	repo.warnings = io.Discard
+	return repo
+}
+
+func TestEventLogRepository_Service(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.jsonl")
+	service := todo.NewStoreService(newTestEventLogRepository(filename))
+
+	for _, description := range []string{"First", "Second", "Third"} {
+		if _, err := service.Add(description); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+	steps := []func() error{
+		func() error { return service.Complete(1) },
+		func() error { return service.Incomplete(1) },
+		func() error { return service.Delete(2) },
+		func() error { _, err := service.Restore(2); return err },
+		func() error { return service.SetPriority(3, todo.PriorityHigh) },
+		func() error { return service.Delete(3) },
+		func() error { _, err := service.PurgeTrash(time.Now().Add(time.Second)); return err },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		types = append(types, strings.SplitN(strings.TrimPrefix(line, `{"type":"`), `"`, 2)[0])
+	}
+	expected := []string{
+		"TodoAdded", "TodoAdded", "TodoAdded", "TodoCompleted", "TodoReopened",
+		"TodoDeleted", "TodoRestored", "TodoUpdated", "TodoDeleted", "TodoRemoved",
+	}
+	if strings.Join(types, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected events %v, got %v", expected, types)
+	}
+
+	reloaded := todo.NewStoreService(newTestEventLogRepository(filename))
+	if todos, err := reloaded.List(todo.ListOptions{}); err != nil || len(todos) != 2 || todos[0].ID != 1 || todos[1].ID != 2 {
+		t.Errorf("Expected todos 1 and 2, got %+v (%v)", todos, err)
+	}
+}
+
+func TestEventLogRepository_LoadAsOf(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.jsonl")
+	repo := newTestEventLogRepository(filename)
+
+	if err := repo.Create(todo.Todo{ID: 1, Description: "Draft"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	drafted := time.Now()
+	if err := repo.Update(todo.Todo{ID: 1, Description: "Final"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected []string
+	}{
+		{"before the first event", drafted.Add(-time.Hour), nil},
+		{"between the events", drafted, []string{"Draft"}},
+		{"now", time.Now(), []string{"Final"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todos, err := repo.LoadAsOf(tt.at)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(todos) != len(tt.expected) {
+				t.Fatalf("Expected %d todos, got %d", len(tt.expected), len(todos))
+			}
+			for i, description := range tt.expected {
+				if todos[i].Description != description {
+					t.Errorf("Expected %q, got %q", description, todos[i].Description)
+				}
+			}
+		})
+	}
+
+	compacted, err := repo.Compact()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if compacted != 2 {
+		t.Errorf("Expected 2 events to be compacted, got %d", compacted)
+	}
+	if _, err := repo.LoadAsOf(drafted); err == nil {
+		t.Error("Expected error reading a compacted time, got nil")
+	}
+
+	// Other instances see the compacted log, and new events follow it.
+	other := newTestEventLogRepository(filename)
+	if got, err := other.Get(1); err != nil || got.Description != "Final" {
+		t.Errorf("Expected the final todo, got %+v (%v)", got, err)
+	}
+	if err := other.Create(todo.Todo{ID: 2, Description: "New"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if todos, err := repo.List(todo.ListOptions{}); err != nil || len(todos) != 2 {
+		t.Errorf("Expected 2 todos, got %+v (%v)", todos, err)
+	}
+}
+
+func TestEventLogRepository_Snapshot(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.jsonl")
+	repo := newTestEventLogRepository(filename)
+
+	for id := 1; id <= snapshotInterval+1; id++ {
+		if err := repo.Create(todo.Todo{ID: id, Description: "Todo"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	var snapshot eventSnapshotFile
+	if err := readJSON(repo.snapshotFilename(), &snapshot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(snapshot.Todos) != snapshotInterval {
+		t.Errorf("Expected %d todos in the snapshot, got %d", snapshotInterval, len(snapshot.Todos))
+	}
+
+	// A snapshot is trusted, so a changed one shows that loading used it.
+	snapshot.Todos[0].Description = "From snapshot"
+	if err := writeJSON(repo.snapshotFilename(), snapshot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	todos, err := newTestEventLogRepository(filename).List(todo.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(todos) != snapshotInterval+1 || todos[0].Description != "From snapshot" {
+		t.Errorf("Expected %d todos loaded from the snapshot, got %d", snapshotInterval+1, len(todos))
+	}
+}
+
+func TestEventLogRepository_TornLastEvent(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.jsonl")
+	if err := newTestEventLogRepository(filename).Create(todo.Todo{ID: 1, Description: "Kept"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A crash while appending leaves part of an event.
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := file.WriteString(`{"type":"TodoAdded","at":"2024-`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_ = file.Close()
+
+	repo := newTestEventLogRepository(filename)
+	if todos, err := repo.List(todo.ListOptions{}); err != nil || len(todos) != 1 {
+		t.Fatalf("Expected the complete event to load, got %+v (%v)", todos, err)
+	}
+	if err := repo.Create(todo.Todo{ID: 2, Description: "Appended"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	todos, err := newTestEventLogRepository(filename).List(todo.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(todos) != 2 || todos[1].Description != "Appended" {
+		t.Errorf("Expected the torn event to be replaced, got %+v", todos)
+	}
+}
+
+func TestEventLogRepository_Conflict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.jsonl")
+	first := newTestEventLogRepository(filename)
+	second := newTestEventLogRepository(filename)
+
+	if _, err := second.List(todo.ListOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := first.Create(todo.Todo{ID: 1, Description: "First"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err := second.Create(todo.Todo{ID: 1, Description: "Second"})
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+
+	if _, err := second.List(todo.ListOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := second.Create(todo.Todo{ID: 2, Description: "Second"}); err != nil {
+		t.Errorf("Unexpected error after reload: %v", err)
+	}
+}
+
+func TestEventLogRepository_SidecarConflict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.jsonl")
+	first := newTestEventLogRepository(filename)
+	second := newTestEventLogRepository(filename)
+
+	for _, repo := range []*EventLogRepository{first, second} {
+		if _, err := repo.List(todo.ListOptions{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.LoadProjects(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Changed projects make appending to the log a conflict.
+	if err := first.SaveProjects([]todo.Project{{Name: "home"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := second.Create(todo.Todo{ID: 1, Description: "Second"})
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+
+	// And a changed log makes saving the projects a conflict.
+	if err := first.Create(todo.Todo{ID: 1, Description: "First"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	third := newTestEventLogRepository(filename)
+	if _, err := third.List(todo.ListOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := first.Create(todo.Todo{ID: 2, Description: "Another"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = third.SaveProjects([]todo.Project{{Name: "work"}})
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+}
+
+func TestEventLogRepository_BesideJSONStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonFilename := filepath.Join(tmpDir, "todos.json")
+	logFilename := filepath.Join(tmpDir, "todos.jsonl")
+
+	// Each command opens the store anew, as separate processes do.
+	if _, err := todo.NewStoreService(newTestEventLogRepository(logFilename)).Add("From events"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if _, err := todo.NewService(NewJSONRepository(jsonFilename)).Add("From JSON"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	// Each store keeps its own journal and history.
+	logService := todo.NewStoreService(newTestEventLogRepository(logFilename))
+	changes, err := logService.History(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, change := range changes {
+		if change.New == "From JSON" {
+			t.Errorf("Expected only the event log's history, got %v", change)
+		}
+	}
+	entries, err := logService.Undo(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].After[0].Description != "From events" {
+		t.Errorf("Expected to undo adding the event log's todo, got %+v", entries)
+	}
+
+	jsonService := todo.NewService(NewJSONRepository(jsonFilename))
+	if todos, err := jsonService.List(todo.ListOptions{}); err != nil || len(todos) != 1 || todos[0].Description != "From JSON" {
+		t.Errorf("Expected the JSON store's todo to be kept, got %+v (%v)", todos, err)
+	}
+	if _, err := os.Stat(logFilename + ".journal.json"); err != nil {
+		t.Errorf("Expected the event log's journal next to it: %v", err)
+	}
+}
@@ -1,9 +1,15 @@
 package storage
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"example.com/todo/internal/todo"
 )
@@ -11,50 +17,425 @@ import (
 // JSONRepository implements todo.Repository using JSON file storage.
 type JSONRepository struct {
 	filename string
+	// sidecarBase is what the names of the files next to the todo file
+	// start with.
+	sidecarBase string
+	// warnings receives messages about recovered problems, such as loading
+	// the backup because the todo file is corrupt.
+	warnings io.Writer
+	// revision identifies the content of the todo file as last loaded or
+	// saved, once tracked is set, to detect changes made by others.
+	revision string
+	tracked  bool
+	// sidecars holds the revisions of the files next to the todo file as
+	// last loaded or saved. They are identified by size and modification
+	// time, so that checking them does not read the whole archive.
+	sidecars map[string]string
 }
 
 // NewJSONRepository creates a new JSON repository.
 func NewJSONRepository(filename string) *JSONRepository {
 	return &JSONRepository{
-		filename: filename,
+		filename:    filename,
+		sidecarBase: strings.TrimSuffix(filename, filepath.Ext(filename)),
+		warnings:    os.Stderr,
 	}
 }
 
-// Save writes todos to a JSON file.
+// Save writes todos to a JSON file. The file is replaced atomically, so a
+// crash leaves either the old or the new version, and the old version is
+// kept as a backup next to it, e.g. data/todos.json.bak. If the file or
+// one of the files next to it was changed since it was loaded, Save returns
+// an error wrapping todo.ErrConflict instead.
 func (r *JSONRepository) Save(todos []todo.Todo) error {
+	if err := r.checkRevisions(); err != nil {
+		return fmt.Errorf("failed to save todos: %w", err)
+	}
+
 	data, err := json.MarshalIndent(todos, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal todos: %w", err)
+		return fmt.Errorf("failed to save todos: failed to marshal: %w", err)
 	}
-
-	if err := os.WriteFile(r.filename, data, 0o600); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if err := r.backup(); err != nil {
+		return fmt.Errorf("failed to save todos: %w", err)
+	}
+	if err := writeFile(r.filename, data); err != nil {
+		return fmt.Errorf("failed to save todos: %w", err)
 	}
 
+	r.revision, r.tracked = revisionOf(data), true
 	return nil
 }
 
-// Load reads todos from a JSON file.
+// Load reads todos from a JSON file. If the file is corrupt or was
+// truncated to nothing, the backup is loaded instead.
 func (r *JSONRepository) Load() ([]todo.Todo, error) {
-	if _, err := os.Stat(r.filename); os.IsNotExist(err) {
-		// File does not exist, start with empty todos.
-		return []todo.Todo{}, nil
+	// The revision is read before the todos, so that a change made in
+	// between is detected as a conflict rather than missed.
+	revision, err := fileRevision(r.filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load todos: %w", err)
+	}
+	r.revision, r.tracked = revision, true
+
+	todos := []todo.Todo{}
+	err = readJSON(r.filename, &todos)
+	if err == nil && !emptyFile(r.filename) {
+		return todos, nil
+	}
+	if !fileExists(r.backupFilename()) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to load todos: %w", err)
+		}
+		return todos, nil
 	}
 
+	backup := []todo.Todo{}
+	if err == nil {
+		err = fmt.Errorf("file is empty")
+	}
+	if backupErr := readJSON(r.backupFilename(), &backup); backupErr != nil {
+		return nil, fmt.Errorf("failed to load todos: %w; backup %s: %v", err, r.backupFilename(), backupErr)
+	}
+
+	_, _ = fmt.Fprintf(r.warnings, "Warning: could not load %s (%v); loaded the previous version from %s instead\n",
+		r.filename, err, r.backupFilename())
+	return backup, nil
+}
+
+// backup copies the todo file to the backup file before it is replaced.
+// A todo file that is missing or cannot be parsed does not replace an
+// earlier backup.
+func (r *JSONRepository) backup() error {
 	data, err := os.ReadFile(r.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) == 0 || !json.Valid(data) {
+		return nil
+	}
+	return writeFile(r.backupFilename(), data)
+}
+
+// backupFilename returns the name of the backup file, e.g.
+// data/todos.json.bak for data/todos.json.
+func (r *JSONRepository) backupFilename() string {
+	return r.filename + ".bak"
+}
+
+// SaveProjects writes projects to a JSON file next to the todo file.
+func (r *JSONRepository) SaveProjects(projects []todo.Project) error {
+	if err := r.saveSidecar("projects", projects); err != nil {
+		return fmt.Errorf("failed to save projects: %w", err)
+	}
+	return nil
+}
+
+// LoadProjects reads projects from the JSON file next to the todo file.
+func (r *JSONRepository) LoadProjects() ([]todo.Project, error) {
+	projects := []todo.Project{}
+	if err := r.loadSidecar("projects", &projects); err != nil {
+		return nil, fmt.Errorf("failed to load projects: %w", err)
+	}
+	return projects, nil
+}
+
+// SaveJournal writes the undo journal to a JSON file next to the todo file.
+func (r *JSONRepository) SaveJournal(journal todo.Journal) error {
+	if err := r.saveSidecar("journal", journal); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
+	return nil
+}
+
+// LoadJournal reads the undo journal from the JSON file next to the todo
+// file.
+func (r *JSONRepository) LoadJournal() (todo.Journal, error) {
+	var journal todo.Journal
+	if err := r.loadSidecar("journal", &journal); err != nil {
+		return todo.Journal{}, fmt.Errorf("failed to load journal: %w", err)
+	}
+	return journal, nil
+}
+
+// SaveArchive writes archived todos to a JSON file next to the todo file.
+func (r *JSONRepository) SaveArchive(todos []todo.Todo) error {
+	if err := r.saveSidecar("archive", todos); err != nil {
+		return fmt.Errorf("failed to save archive: %w", err)
+	}
+	return nil
+}
+
+// LoadArchive reads archived todos from the JSON file next to the todo file.
+func (r *JSONRepository) LoadArchive() ([]todo.Todo, error) {
+	todos := []todo.Todo{}
+	if err := r.loadSidecar("archive", &todos); err != nil {
+		return nil, fmt.Errorf("failed to load archive: %w", err)
+	}
+	return todos, nil
+}
+
+// idsFile is the content of the file that keeps the highest todo ID given.
+type idsFile struct {
+	LastID int `json:"last_id"`
+}
+
+// SaveLastID writes the highest todo ID given to a JSON file next to the
+// todo file.
+func (r *JSONRepository) SaveLastID(id int) error {
+	if err := r.saveSidecar("ids", idsFile{LastID: id}); err != nil {
+		return fmt.Errorf("failed to save last ID: %w", err)
+	}
+	return nil
+}
+
+// LoadLastID reads the highest todo ID given from the JSON file next to the
+// todo file.
+func (r *JSONRepository) LoadLastID() (int, error) {
+	var ids idsFile
+	if err := r.loadSidecar("ids", &ids); err != nil {
+		return 0, fmt.Errorf("failed to load last ID: %w", err)
+	}
+	return ids.LastID, nil
+}
+
+// AppendHistory adds changes to the JSON Lines history file next to the todo
+// file, without rewriting it.
+func (r *JSONRepository) AppendHistory(changes []todo.Change) error {
+	var data []byte
+	for _, change := range changes {
+		line, err := json.Marshal(change)
+		if err != nil {
+			return fmt.Errorf("failed to save history: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	file, err := os.OpenFile(r.historyFilename(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory reads the history file next to the todo file.
+func (r *JSONRepository) LoadHistory() ([]todo.Change, error) {
+	data, err := os.ReadFile(r.historyFilename())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+
+	var changes []todo.Change
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var change todo.Change
+		if err := json.Unmarshal(line, &change); err != nil {
+			return nil, fmt.Errorf("failed to load history: line %d: %w", i+1, err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// saveSidecar writes v as JSON to a file next to the todo file. Like Save,
+// it returns an error wrapping todo.ErrConflict if any of the files was
+// changed since it was loaded.
+func (r *JSONRepository) saveSidecar(kind string, v any) error {
+	if err := r.checkRevisions(); err != nil {
+		return err
+	}
+	filename := r.sidecarFilename(kind)
+	if err := writeJSON(filename, v); err != nil {
+		return err
+	}
+	return r.trackSidecar(filename)
+}
+
+// loadSidecar reads the JSON in a file next to the todo file into v.
+func (r *JSONRepository) loadSidecar(kind string, v any) error {
+	// As with the todo file, the revision is read first.
+	filename := r.sidecarFilename(kind)
+	if err := r.trackSidecar(filename); err != nil {
+		return err
+	}
+	return readJSON(filename, v)
+}
+
+func (r *JSONRepository) trackSidecar(filename string) error {
+	revision, err := statRevision(filename)
+	if err != nil {
+		return err
+	}
+	if r.sidecars == nil {
+		r.sidecars = make(map[string]string)
+	}
+	r.sidecars[filename] = revision
+	return nil
+}
+
+// checkRevisions returns todo.ErrConflict if the todo file or a file next
+// to it changed since it was last loaded or saved.
+func (r *JSONRepository) checkRevisions() error {
+	if r.tracked {
+		current, err := fileRevision(r.filename)
+		if err != nil {
+			return err
+		}
+		if current != r.revision {
+			return todo.ErrConflict
+		}
+	}
+	for filename, revision := range r.sidecars {
+		current, err := statRevision(filename)
+		if err != nil {
+			return err
+		}
+		if current != revision {
+			return todo.ErrConflict
+		}
+	}
+	return nil
+}
+
+// historyFilename returns the name of the history file, e.g.
+// data/todos.history.jsonl for data/todos.json.
+func (r *JSONRepository) historyFilename() string {
+	return strings.TrimSuffix(r.sidecarFilename("history"), ".json") + ".jsonl"
+}
+
+// sidecarFilename returns the name of a companion file stored next to the
+// todo file, e.g. data/todos.projects.json for data/todos.json.
+func (r *JSONRepository) sidecarFilename(kind string) string {
+	return r.sidecarBase + "." + kind + ".json"
+}
+
+// writeJSON marshals v as indented JSON into filename.
+func writeJSON(filename string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	return writeFile(filename, data)
+}
+
+// writeFile atomically replaces filename with data: it writes a temporary
+// file in the same directory, syncs it to disk and renames it over filename.
+func writeFile(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	tmpName := file.Name()
+	defer func() {
+		// Clean up after a failure; after the rename the file is gone.
+		_ = os.Remove(tmpName)
+	}()
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmpName, filename); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	// Sync the directory so the rename itself survives a crash. Not every
+	// platform supports this, so failures are ignored.
+	if dirFile, err := os.Open(dir); err == nil {
+		_ = dirFile.Sync()
+		_ = dirFile.Close()
+	}
+
+	return nil
+}
+
+// fileRevision identifies the content of a file, or returns "" if it does
+// not exist.
+func fileRevision(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return revisionOf(data), nil
+}
+
+// statRevision identifies a file by its size and modification time, or
+// returns "" if it does not exist.
+func statRevision(filename string) (string, error) {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano()), nil
+}
+
+func revisionOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+func emptyFile(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && info.Size() == 0
+}
+
+// readJSON unmarshals the JSON in filename into v. A missing or empty file
+// leaves v untouched.
+func readJSON(filename string, v any) error {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		// File does not exist, keep the empty value.
+		return nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
 	}
 
 	if len(data) == 0 {
 		// Empty file.
-		return []todo.Todo{}, nil
+		return nil
 	}
 
-	var todos []todo.Todo
-	if err := json.Unmarshal(data, &todos); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal todos: %w", err)
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
 	}
 
-	return todos, nil
+	return nil
 }
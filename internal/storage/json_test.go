@@ -2,8 +2,11 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -365,3 +368,435 @@ func TestJSONRepository_SaveAndLoad_Integration(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONRepository_SaveAndLoadProjects(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "todos.json")
+	repo := NewJSONRepository(filename)
+
+	// Loading before anything is saved yields no projects.
+	projects, err := repo.LoadProjects()
+	if err != nil {
+		t.Fatalf("Failed to load projects: %v", err)
+	}
+
+	if len(projects) != 0 {
+		t.Errorf("Expected 0 projects, got %d", len(projects))
+	}
+
+	original := []todo.Project{
+		{Name: "backend", CreatedAt: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{Name: "docs", Archived: true, CreatedAt: time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC)},
+	}
+
+	if err := repo.SaveProjects(original); err != nil {
+		t.Fatalf("Failed to save projects: %v", err)
+	}
+
+	// Projects are stored next to the todo file.
+	if _, err := os.Stat(filepath.Join(tmpDir, "todos.projects.json")); err != nil {
+		t.Errorf("Expected projects file to exist: %v", err)
+	}
+
+	loaded, err := repo.LoadProjects()
+	if err != nil {
+		t.Fatalf("Failed to load projects: %v", err)
+	}
+
+	if len(loaded) != len(original) {
+		t.Fatalf("Expected %d projects, got %d", len(original), len(loaded))
+	}
+
+	for i, expected := range original {
+		if loaded[i].Name != expected.Name {
+			t.Errorf("Project %d: expected name %s, got %s", i, expected.Name, loaded[i].Name)
+		}
+		if loaded[i].Archived != expected.Archived {
+			t.Errorf("Project %d: expected archived %t, got %t", i, expected.Archived, loaded[i].Archived)
+		}
+	}
+}
+
+func TestJSONRepository_SaveAndLoadJournal(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "todos.json")
+	repo := NewJSONRepository(filename)
+
+	// Loading before anything is saved yields an empty journal.
+	journal, err := repo.LoadJournal()
+	if err != nil {
+		t.Fatalf("Failed to load journal: %v", err)
+	}
+
+	if len(journal.Undo) != 0 || len(journal.Redo) != 0 {
+		t.Errorf("Expected empty journal, got %+v", journal)
+	}
+
+	original := todo.Journal{
+		Undo: []todo.JournalEntry{
+			{
+				Action: todo.ActionComplete,
+				ID:     1,
+				At:     time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+				Before: []todo.Todo{{ID: 1, Description: "Test"}},
+				After:  []todo.Todo{{ID: 1, Description: "Test", Completed: true}},
+			},
+		},
+		Redo: []todo.JournalEntry{
+			{Action: todo.ActionAdd, ID: 2, After: []todo.Todo{{ID: 2, Description: "Undone"}}},
+		},
+	}
+
+	if err := repo.SaveJournal(original); err != nil {
+		t.Fatalf("Failed to save journal: %v", err)
+	}
+
+	// The journal is stored next to the todo file.
+	if _, err := os.Stat(filepath.Join(tmpDir, "todos.journal.json")); err != nil {
+		t.Errorf("Expected journal file to exist: %v", err)
+	}
+
+	loaded, err := repo.LoadJournal()
+	if err != nil {
+		t.Fatalf("Failed to load journal: %v", err)
+	}
+
+	if len(loaded.Undo) != 1 || len(loaded.Redo) != 1 {
+		t.Fatalf("Expected 1 undo and 1 redo entry, got %d and %d", len(loaded.Undo), len(loaded.Redo))
+	}
+
+	entry := loaded.Undo[0]
+	if entry.Action != todo.ActionComplete || entry.ID != 1 {
+		t.Errorf("Expected complete #1, got %s", entry)
+	}
+	if len(entry.Before) != 1 || entry.Before[0].Completed {
+		t.Errorf("Expected pending todo before the change, got %+v", entry.Before)
+	}
+	if len(entry.After) != 1 || !entry.After[0].Completed {
+		t.Errorf("Expected completed todo after the change, got %+v", entry.After)
+	}
+	if loaded.Redo[0].Before != nil {
+		t.Errorf("Expected no todos before an add, got %+v", loaded.Redo[0].Before)
+	}
+}
+
+func TestJSONRepository_SaveAndLoadArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "todos.json")
+	repo := NewJSONRepository(filename)
+
+	// Loading before anything is archived yields no todos.
+	archived, err := repo.LoadArchive()
+	if err != nil {
+		t.Fatalf("Failed to load archive: %v", err)
+	}
+
+	if len(archived) != 0 {
+		t.Errorf("Expected 0 archived todos, got %d", len(archived))
+	}
+
+	completedAt := time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC)
+	original := []todo.Todo{
+		{ID: 1, Description: "Done", Completed: true, CompletedAt: &completedAt},
+	}
+
+	if err := repo.SaveArchive(original); err != nil {
+		t.Fatalf("Failed to save archive: %v", err)
+	}
+
+	// The archive is stored next to the todo file, which stays untouched.
+	if _, err := os.Stat(filepath.Join(tmpDir, "todos.archive.json")); err != nil {
+		t.Errorf("Expected archive file to exist: %v", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected todo file not to be written, got %v", err)
+	}
+
+	loaded, err := repo.LoadArchive()
+	if err != nil {
+		t.Fatalf("Failed to load archive: %v", err)
+	}
+
+	if len(loaded) != 1 || loaded[0].ID != 1 || !loaded[0].Completed {
+		t.Fatalf("Expected archived todo 1, got %+v", loaded)
+	}
+	if loaded[0].CompletedAt == nil || !loaded[0].CompletedAt.Equal(completedAt) {
+		t.Errorf("Expected CompletedAt %v, got %v", completedAt, loaded[0].CompletedAt)
+	}
+}
+
+func TestJSONRepository_SaveAndLoadLastID(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := NewJSONRepository(filepath.Join(tmpDir, "todos.json"))
+
+	// Loading before an ID is saved yields 0.
+	lastID, err := repo.LoadLastID()
+	if err != nil {
+		t.Fatalf("Failed to load last ID: %v", err)
+	}
+	if lastID != 0 {
+		t.Errorf("Expected last ID 0, got %d", lastID)
+	}
+
+	if err := repo.SaveLastID(42); err != nil {
+		t.Fatalf("Failed to save last ID: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "todos.ids.json")); err != nil {
+		t.Errorf("Expected ID file to exist: %v", err)
+	}
+
+	lastID, err = NewJSONRepository(filepath.Join(tmpDir, "todos.json")).LoadLastID()
+	if err != nil {
+		t.Fatalf("Failed to load last ID: %v", err)
+	}
+	if lastID != 42 {
+		t.Errorf("Expected last ID 42, got %d", lastID)
+	}
+}
+
+func TestJSONRepository_AppendAndLoadHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := NewJSONRepository(filepath.Join(tmpDir, "todos.json"))
+
+	// Loading before anything is recorded yields no changes.
+	history, err := repo.LoadHistory()
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+
+	if len(history) != 0 {
+		t.Errorf("Expected 0 changes, got %d", len(history))
+	}
+
+	at := time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC)
+	batches := [][]todo.Change{
+		{{TodoID: 1, Action: todo.ActionAdd, Field: todo.FieldTodo, New: "Buy milk", By: "alice", At: at}},
+		{
+			{TodoID: 1, Action: todo.ActionEdit, Field: "priority", Old: "none", New: "high", At: at},
+			{TodoID: 1, Action: todo.ActionEdit, Field: "due", Old: "", New: "2023-01-05", At: at},
+		},
+	}
+
+	for _, batch := range batches {
+		if err := repo.AppendHistory(batch); err != nil {
+			t.Fatalf("Failed to append history: %v", err)
+		}
+	}
+
+	// Each change is one line of the history file next to the todo file.
+	data, err := os.ReadFile(filepath.Join(tmpDir, "todos.history.jsonl"))
+	if err != nil {
+		t.Fatalf("Expected history file to exist: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf("Expected 3 lines, got %d", lines)
+	}
+
+	loaded, err := repo.LoadHistory()
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+
+	if len(loaded) != 3 {
+		t.Fatalf("Expected 3 changes, got %d", len(loaded))
+	}
+	if loaded[0].By != "alice" || loaded[0].New != "Buy milk" || !loaded[0].At.Equal(at) {
+		t.Errorf("Expected first change to round-trip, got %+v", loaded[0])
+	}
+	if loaded[2].Field != "due" || loaded[2].New != "2023-01-05" {
+		t.Errorf("Expected due change, got %+v", loaded[2])
+	}
+}
+
+func TestJSONRepository_Save_KeepsBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "todos.json")
+	repo := NewJSONRepository(filename)
+
+	first := []todo.Todo{{ID: 1, Description: "First"}}
+	second := []todo.Todo{{ID: 1, Description: "First"}, {ID: 2, Description: "Second"}}
+
+	if err := repo.Save(first); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+	if _, err := os.Stat(filename + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup after the first save, got %v", err)
+	}
+
+	if err := repo.Save(second); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+
+	backup, err := NewJSONRepository(filename + ".bak").Load()
+	if err != nil {
+		t.Fatalf("Failed to load backup: %v", err)
+	}
+	if len(backup) != 1 {
+		t.Errorf("Expected the previous version with 1 todo in the backup, got %d", len(backup))
+	}
+
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the todo file and its backup, got %d files", len(entries))
+	}
+
+	// A corrupt todo file does not replace the backup.
+	if err := os.WriteFile(filename, []byte(`[{"id": 1,`), 0o600); err != nil {
+		t.Fatalf("Failed to corrupt file: %v", err)
+	}
+	repo.warnings = io.Discard
+	if _, err := repo.Load(); err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+	if err := repo.Save(second); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+	backup, err = NewJSONRepository(filename + ".bak").Load()
+	if err != nil {
+		t.Fatalf("Failed to load backup: %v", err)
+	}
+	if len(backup) != 1 {
+		t.Errorf("Expected the backup to be kept, got %d todos", len(backup))
+	}
+}
+
+func TestJSONRepository_Load_FallsBackToBackup(t *testing.T) {
+	tests := []struct {
+		name        string
+		primary     string
+		backup      string
+		expectError bool
+		expected    int
+		warns       bool
+	}{
+		{"valid", `[{"id": 1}, {"id": 2}]`, `[{"id": 1}]`, false, 2, false},
+		{"truncated", `[{"id": 1}, {"id"`, `[{"id": 1}]`, false, 1, true},
+		{"empty", ``, `[{"id": 1}]`, false, 1, true},
+		{"empty without backup", ``, "", false, 0, false},
+		{"truncated without backup", `[{"id"`, "", true, 0, false},
+		{"backup corrupt too", `[{"id"`, `[{`, true, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			filename := filepath.Join(tmpDir, "todos.json")
+			if err := os.WriteFile(filename, []byte(tt.primary), 0o600); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			if tt.backup != "" {
+				if err := os.WriteFile(filename+".bak", []byte(tt.backup), 0o600); err != nil {
+					t.Fatalf("Failed to write backup: %v", err)
+				}
+			}
+
+			var warnings strings.Builder
+			repo := NewJSONRepository(filename)
+			repo.warnings = &warnings
+
+			todos, err := repo.Load()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(todos) != tt.expected {
+				t.Errorf("Expected %d todos, got %d", tt.expected, len(todos))
+			}
+			if tt.warns != strings.Contains(warnings.String(), filename+".bak") {
+				t.Errorf("Expected warning %v, got %q", tt.warns, warnings.String())
+			}
+		})
+	}
+}
+
+func TestJSONRepository_Save_Conflict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.json")
+	first := NewJSONRepository(filename)
+	second := NewJSONRepository(filename)
+
+	if err := first.Save([]todo.Todo{{ID: 1, Description: "First"}}); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+	if _, err := second.Load(); err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+
+	// The first repository saved last, so it can save again.
+	if err := first.Save([]todo.Todo{{ID: 1, Description: "Changed"}}); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+
+	// The second repository loaded before that change.
+	err := second.Save([]todo.Todo{{ID: 1, Description: "Overwritten"}})
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+
+	loaded, err := second.Load()
+	if err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+	if loaded[0].Description != "Changed" {
+		t.Errorf("Expected the other change to be kept, got %q", loaded[0].Description)
+	}
+
+	// After loading again it saves.
+	if err := second.Save(loaded); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestJSONRepository_Save_SidecarConflict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.json")
+	first := NewJSONRepository(filename)
+	second := NewJSONRepository(filename)
+
+	for _, repo := range []*JSONRepository{first, second} {
+		if _, err := repo.Load(); err != nil {
+			t.Fatalf("Failed to load todos: %v", err)
+		}
+		if _, err := repo.LoadProjects(); err != nil {
+			t.Fatalf("Failed to load projects: %v", err)
+		}
+	}
+
+	if err := first.SaveProjects([]todo.Project{{Name: "home"}}); err != nil {
+		t.Fatalf("Failed to save projects: %v", err)
+	}
+
+	// The second repository loaded the projects before they changed, so it
+	// saves neither the todos nor other files.
+	err := second.Save([]todo.Todo{{ID: 1, Description: "Second"}})
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Errorf("Expected ErrConflict saving todos, got %v", err)
+	}
+	err = second.SaveJournal(todo.Journal{})
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Errorf("Expected ErrConflict saving the journal, got %v", err)
+	}
+	err = second.SaveProjects([]todo.Project{{Name: "work"}})
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Errorf("Expected ErrConflict saving projects, got %v", err)
+	}
+
+	projects, err := second.LoadProjects()
+	if err != nil {
+		t.Fatalf("Failed to load projects: %v", err)
+	}
+	if len(projects) != 1 || projects[0].Name != "home" {
+		t.Errorf("Expected the other change to be kept, got %+v", projects)
+	}
+	if err := second.SaveProjects(append(projects, todo.Project{Name: "work"})); err != nil {
+		t.Errorf("Unexpected error after reload: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockPollInterval is how often a locked store is retried while waiting.
+const lockPollInterval = 50 * time.Millisecond
+
+// errWouldBlock is returned by tryLock when another process holds the lock.
+var errWouldBlock = errors.New("lock is held by another process")
+
+// LockedError is returned by Lock when another process kept the store locked
+// for longer than the timeout.
+type LockedError struct {
+	// PID is the process holding the lock, or 0 if it is not known.
+	PID int
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return "store is locked by another process"
+	}
+	return fmt.Sprintf("store is locked by pid %d", e.PID)
+}
+
+// Lock takes an exclusive advisory lock on the store, so that another
+// process cannot load and save it between this process loading and saving
+// it. It waits up to timeout for other processes to release the lock and
+// returns a *LockedError if they do not. The returned function releases the
+// lock.
+//
+// The lock is held on a separate file, e.g. data/todos.json.lock, because
+// saving replaces the todo file.
+func (r *JSONRepository) Lock(timeout time.Duration) (func() error, error) {
+	file, err := os.OpenFile(r.lockFilename(), os.O_RDWR|os.O_CREATE, 0o600)
+	if os.IsNotExist(err) {
+		// The directory does not exist, so there is nothing to protect
+		// yet; saving fails until it is created.
+		return func() error { return nil }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock store: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tryLock(file)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errWouldBlock) {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to lock store: %w", err)
+		}
+		if time.Now().After(deadline) {
+			_ = file.Close()
+			return nil, &LockedError{PID: r.lockHolder()}
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	// Record the holder for processes that have to wait.
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return func() error {
+		// Closing the file releases the lock.
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to unlock store: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// lockHolder returns the pid recorded in the lock file, or 0.
+func (r *JSONRepository) lockHolder() int {
+	data, err := os.ReadFile(r.lockFilename())
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// lockFilename returns the name of the lock file, e.g. data/todos.json.lock
+// for data/todos.json.
+func (r *JSONRepository) lockFilename() string {
+	return r.filename + ".lock"
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// tryLock does nothing on platforms without flock, where concurrent
+// processes are not protected from each other.
+func tryLock(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,99 @@
+//go:build unix
+
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+func TestJSONRepository_Lock(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.json")
+	first := NewJSONRepository(filename)
+	second := NewJSONRepository(filename)
+
+	unlock, err := first.Lock(time.Second)
+	if err != nil {
+		t.Fatalf("Failed to lock store: %v", err)
+	}
+
+	// Another lock on the same store waits and then reports the holder.
+	start := time.Now()
+	_, err = second.Lock(100 * time.Millisecond)
+	var lockedErr *LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+	if lockedErr.PID != os.Getpid() {
+		t.Errorf("Expected pid %d, got %d", os.Getpid(), lockedErr.PID)
+	}
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Errorf("Expected to wait for the timeout, waited %v", waited)
+	}
+
+	// A lock released while waiting is taken.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = unlock()
+	}()
+	unlockSecond, err := second.Lock(5 * time.Second)
+	if err != nil {
+		t.Fatalf("Failed to lock store: %v", err)
+	}
+	if err := unlockSecond(); err != nil {
+		t.Errorf("Failed to unlock store: %v", err)
+	}
+}
+
+func TestJSONRepository_Lock_MissingDirectory(t *testing.T) {
+	repo := NewJSONRepository(filepath.Join(t.TempDir(), "missing", "todos.json"))
+
+	unlock, err := repo.Lock(0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := unlock(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestJSONRepository_Lock_Service(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.json")
+	service := todo.NewService(NewJSONRepository(filename))
+	if _, err := service.Add("Before"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	// The service only holds the lock while it changes the todos.
+	unlock, err := NewJSONRepository(filename).Lock(0)
+	if err != nil {
+		t.Fatalf("Failed to lock store: %v", err)
+	}
+
+	reader := todo.NewService(NewJSONRepository(filename))
+	if todos, err := reader.List(todo.ListOptions{}); err != nil || len(todos) != 1 {
+		t.Errorf("Expected to read 1 todo while the store is locked, got %+v (%v)", todos, err)
+	}
+	var lockedErr *LockedError
+	if _, err := service.Add("While locked"); !errors.As(err, &lockedErr) {
+		t.Errorf("Expected LockedError, got %v", err)
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatalf("Failed to unlock store: %v", err)
+	}
+	if _, err := service.Add("After"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if _, err := reader.Add("From reader"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if todos, err := reader.List(todo.ListOptions{}); err != nil || len(todos) != 3 {
+		t.Errorf("Expected 3 todos, got %+v (%v)", todos, err)
+	}
+}
@@ -0,0 +1,1147 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/todo/internal/todo"
+
+	// Registers the pure-Go "sqlite" driver, so no cgo is required.
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the tables on first use. Tags, blockers and notes
+// live in their own tables so they can be queried, and archived todos stay
+// in the todos table with archived set. Due times are stored in UTC, so
+// their text orders them, with the offset they read back in.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS todos (
+	id           INTEGER PRIMARY KEY,
+	description  TEXT    NOT NULL,
+	completed    INTEGER NOT NULL DEFAULT 0,
+	priority     INTEGER NOT NULL DEFAULT 0,
+	created_at   TEXT    NOT NULL,
+	completed_at TEXT,
+	due_at       TEXT,
+	due_offset   INTEGER,
+	project      TEXT,
+	updated_at   TEXT,
+	parent_id    INTEGER,
+	recurrence   TEXT,
+	series_id    INTEGER,
+	deleted_at   TEXT,
+	archived     INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS todos_status ON todos (archived, completed);
+CREATE INDEX IF NOT EXISTS todos_due ON todos (due_at);
+CREATE INDEX IF NOT EXISTS todos_project ON todos (project);
+CREATE INDEX IF NOT EXISTS todos_parent ON todos (parent_id);
+CREATE INDEX IF NOT EXISTS todos_series ON todos (series_id);
+
+CREATE TABLE IF NOT EXISTS todo_tags (
+	todo_id  INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	tag      TEXT    NOT NULL,
+	PRIMARY KEY (todo_id, position)
+);
+CREATE INDEX IF NOT EXISTS todo_tags_tag ON todo_tags (tag);
+
+CREATE TABLE IF NOT EXISTS todo_blockers (
+	todo_id    INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	position   INTEGER NOT NULL,
+	blocker_id INTEGER NOT NULL,
+	PRIMARY KEY (todo_id, position)
+);
+CREATE INDEX IF NOT EXISTS todo_blockers_blocker ON todo_blockers (blocker_id);
+
+CREATE TABLE IF NOT EXISTS todo_notes (
+	todo_id    INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	position   INTEGER NOT NULL,
+	text       TEXT    NOT NULL,
+	created_at TEXT    NOT NULL,
+	PRIMARY KEY (todo_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS projects (
+	name        TEXT    PRIMARY KEY,
+	position    INTEGER NOT NULL,
+	archived    INTEGER NOT NULL DEFAULT 0,
+	created_at  TEXT    NOT NULL,
+	archived_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS history (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	todo_id INTEGER NOT NULL,
+	action  TEXT    NOT NULL,
+	field   TEXT    NOT NULL,
+	old     TEXT    NOT NULL DEFAULT '',
+	new     TEXT    NOT NULL DEFAULT '',
+	by      TEXT    NOT NULL DEFAULT '',
+	at      TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS history_todo ON history (todo_id);
+CREATE INDEX IF NOT EXISTS history_at ON history (at);
+
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+// sqliteVersion is the user_version of databases migrated to the current
+// schema: 1 stores due times in UTC with their offset apart.
+const sqliteVersion = 1
+
+// Keys of the meta table.
+const (
+	metaRevision = "revision"
+	metaJournal  = "journal"
+	metaLastID   = "last_id"
+)
+
+// SQLiteRepository implements todo.Repository and todo.BatchStore using a
+// SQLite database. Unlike JSONRepository, it only writes the todos that
+// changed.
+type SQLiteRepository struct {
+	db *sql.DB
+	// lock locks the database against mutations of other processes, e.g.
+	// data/todos.db.lock for data/todos.db.
+	lock *JSONRepository
+	// saved holds the active todos as last loaded or saved, to find the
+	// rows to write, or nil when they are not known, and revision counts
+	// the saves made to the database, including those of the archive,
+	// projects, journal and last ID, to detect changes made by others, once
+	// tracked is set.
+	saved    map[int]todo.Todo
+	revision int64
+	tracked  bool
+	// tx is the transaction of the running batch, and written holds the
+	// todos it wrote, or nil for the ones it deleted, to update saved once
+	// it is committed.
+	tx      *sql.Tx
+	written map[int]*todo.Todo
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// NewSQLiteRepository opens the SQLite database at path, creating it and its
+// tables if needed.
+func NewSQLiteRepository(path string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// A single connection keeps the pragmas and avoids locking against
+	// ourselves.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := migrateSQLite(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return &SQLiteRepository{db: db, lock: NewJSONRepository(path)}, nil
+}
+
+// Lock locks the database; see JSONRepository.Lock. A mutation reads the
+// todos in several transactions before it saves them, so SQLite's own
+// locks, which last a transaction, do not keep others from saving between
+// them.
+func (r *SQLiteRepository) Lock(timeout time.Duration) (func() error, error) {
+	return r.lock.Lock(timeout)
+}
+
+// migrateSQLite brings a database created by an earlier version up to the
+// current schema.
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version >= sqliteVersion {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Due times were stored as RFC 3339 text with their offset.
+	var hasOffset bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM pragma_table_info('todos') WHERE name = 'due_offset')`).Scan(&hasOffset)
+	if err != nil {
+		return err
+	}
+	if !hasOffset {
+		if _, err := tx.Exec(`ALTER TABLE todos ADD COLUMN due_offset INTEGER`); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.Query(`SELECT id, due_at FROM todos WHERE due_at IS NOT NULL AND due_offset IS NULL`)
+	if err != nil {
+		return err
+	}
+	dueTimes := make(map[int]time.Time)
+	for rows.Next() {
+		var id int
+		var dueAt string
+		if err := rows.Scan(&id, &dueAt); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if dueTimes[id], err = parseSQLTime(dueAt); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("todo %d: %w", id, err)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, dueAt := range dueTimes {
+		due, offset := formatSQLDueTime(&dueAt)
+		if _, err := tx.Exec(`UPDATE todos SET due_at = ?, due_offset = ? WHERE id = ?`, due, offset, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`PRAGMA user_version = ` + strconv.Itoa(sqliteVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close closes the database.
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// Save writes the todos that changed since they were last loaded or saved
+// and removes the ones that are gone, in one transaction. If another
+// process saved in the meantime, Save returns an error wrapping
+// todo.ErrConflict instead.
+func (r *SQLiteRepository) Save(todos []todo.Todo) error {
+	saved, err := r.saveTx(func(tx *sql.Tx) error {
+		previous := r.saved
+		if previous == nil {
+			// Without a load to compare with, every row is written and
+			// every other active row removed.
+			var err error
+			if previous, err = activeIDs(tx); err != nil {
+				return err
+			}
+		}
+
+		seen := make(map[int]bool, len(todos))
+		for _, t := range todos {
+			seen[t.ID] = true
+			if old, ok := previous[t.ID]; ok && reflect.DeepEqual(old, t) {
+				continue
+			}
+			if err := writeTodo(tx, t, false); err != nil {
+				return err
+			}
+		}
+		for id := range previous {
+			if seen[id] {
+				continue
+			}
+			// Archived todos are kept by SaveArchive.
+			if _, err := tx.Exec(`DELETE FROM todos WHERE id = ? AND archived = 0`, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save todos: %w", err)
+	}
+
+	r.saved = todoMap(todos)
+	r.revision, r.tracked = saved, true
+	return nil
+}
+
+// Load reads the active todos, including the trash.
+func (r *SQLiteRepository) Load() ([]todo.Todo, error) {
+	var todos []todo.Todo
+	var revision int64
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		if revision, err = readRevision(tx); err != nil {
+			return err
+		}
+		todos, err = readTodos(tx, activeTodos)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load todos: %w", err)
+	}
+
+	r.saved = todoMap(todos)
+	r.revision, r.tracked = revision, true
+	return todos, nil
+}
+
+// Create adds an active todo.
+func (r *SQLiteRepository) Create(t todo.Todo) error {
+	return r.write(t.ID, &t, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM todos WHERE id = ?)`, t.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("todo with ID %d already exists", t.ID)
+		}
+		return writeTodo(tx, t, false)
+	})
+}
+
+// Update replaces an active todo.
+func (r *SQLiteRepository) Update(t todo.Todo) error {
+	return r.write(t.ID, &t, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM todos WHERE id = ? AND archived = 0)`, t.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: #%d", todo.ErrNotFound, t.ID)
+		}
+		return writeTodo(tx, t, false)
+	})
+}
+
+// Delete removes an active todo. A todo that SaveArchive moved into the
+// archive is no longer active, so it is kept.
+func (r *SQLiteRepository) Delete(id int) error {
+	return r.write(id, nil, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM todos WHERE id = ?)`, id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: #%d", todo.ErrNotFound, id)
+		}
+		_, err := tx.Exec(`DELETE FROM todos WHERE id = ? AND archived = 0`, id)
+		return err
+	})
+}
+
+// Get reads an active todo.
+func (r *SQLiteRepository) Get(id int) (todo.Todo, error) {
+	var todos []todo.Todo
+	err := r.read(func(q queryer) error {
+		var err error
+		todos, err = readTodos(q, activeTodos+` AND t.id = ?`, id)
+		return err
+	})
+	if err != nil {
+		return todo.Todo{}, fmt.Errorf("failed to load todo: %w", err)
+	}
+	if len(todos) == 0 {
+		return todo.Todo{}, fmt.Errorf("%w: #%d", todo.ErrNotFound, id)
+	}
+	return todos[0], nil
+}
+
+// List reads the active todos selected by opts. Their status, tags and due
+// dates are selected in SQL, using the indexes on them, and when they are
+// sorted on ID, due time or priority, only the requested page is read.
+func (r *SQLiteRepository) List(opts todo.ListOptions) ([]todo.Todo, error) {
+	where, args := listCondition(opts)
+	order, ordered := listOrder(opts.Sort)
+
+	var todos []todo.Todo
+	err := r.read(func(q queryer) error {
+		var err error
+		if !ordered {
+			// The selected todos are sorted and paged here instead.
+			if todos, err = readTodos(q, where, args...); err == nil {
+				todos, err = todo.ListOptions{Sort: opts.Sort, Offset: opts.Offset, Limit: opts.Limit}.Apply(todos)
+			}
+			return err
+		}
+
+		// SQLite reads a negative limit as no limit.
+		limit := -1
+		if opts.Limit > 0 {
+			limit = opts.Limit
+		}
+		page := `t.id IN (SELECT t.id FROM todos t WHERE ` + where + ` ORDER BY ` + order + ` LIMIT ? OFFSET ?)`
+		if todos, err = readTodos(q, page, append(args, limit, max(opts.Offset, 0))...); err != nil {
+			return err
+		}
+		// readTodos orders the page by ID.
+		todos, err = todo.ListOptions{Sort: opts.Sort}.Apply(todos)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load todos: %w", err)
+	}
+	return todos, nil
+}
+
+// Batch runs fn, writing the todos it creates, updates and deletes in one
+// transaction that is committed once fn succeeds. If another process saved
+// since the todos were last read or written, Batch returns an error
+// wrapping todo.ErrConflict instead.
+func (r *SQLiteRepository) Batch(fn func() error) error {
+	if r.tx != nil {
+		return fn()
+	}
+
+	written := make(map[int]*todo.Todo)
+	saved, err := r.saveTx(func(tx *sql.Tx) error {
+		r.tx, r.written = tx, written
+		defer func() { r.tx, r.written = nil, nil }()
+		return fn()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save todos: %w", err)
+	}
+
+	// Without the todos as last loaded, Save writes all of them anyway.
+	if r.saved != nil {
+		for id, t := range written {
+			if t == nil {
+				delete(r.saved, id)
+				continue
+			}
+			maps.Copy(r.saved, todoMap([]todo.Todo{*t}))
+		}
+	}
+	r.revision, r.tracked = saved, true
+	return nil
+}
+
+// write runs fn, which writes the todo with the given ID, or deletes it if
+// t is nil, in the running batch or else in a batch of its own.
+func (r *SQLiteRepository) write(id int, t *todo.Todo, fn func(tx *sql.Tx) error) error {
+	if r.tx == nil {
+		return r.Batch(func() error {
+			return r.write(id, t, fn)
+		})
+	}
+	if err := fn(r.tx); err != nil {
+		return err
+	}
+	r.written[id] = t
+	return nil
+}
+
+// read runs fn, which reads todos, in the running batch, so that it sees
+// its writes, or else in a transaction of its own. Outside a batch, it
+// records the revision read, so that later writes find the changes others
+// make from then on.
+func (r *SQLiteRepository) read(fn func(q queryer) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+
+	var revision int64
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		if revision, err = readRevision(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !r.tracked || revision != r.revision {
+		// Others saved since saved was read, so it no longer tells which
+		// rows Save needs to write.
+		r.saved = nil
+	}
+	r.revision, r.tracked = revision, true
+	return nil
+}
+
+// SaveArchive writes the archived todos, moving todos that were active into
+// the archive.
+func (r *SQLiteRepository) SaveArchive(todos []todo.Todo) error {
+	err := r.saveSidecar(func(tx *sql.Tx) error {
+		keep := make([]string, 0, len(todos))
+		for _, t := range todos {
+			if err := writeTodo(tx, t, true); err != nil {
+				return err
+			}
+			keep = append(keep, strconv.Itoa(t.ID))
+		}
+
+		query := `DELETE FROM todos WHERE archived = 1`
+		if len(keep) > 0 {
+			query += ` AND id NOT IN (` + strings.Join(keep, ",") + `)`
+		}
+		_, err := tx.Exec(query)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save archive: %w", err)
+	}
+	return nil
+}
+
+// LoadArchive reads the archived todos.
+func (r *SQLiteRepository) LoadArchive() ([]todo.Todo, error) {
+	var todos []todo.Todo
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		todos, err = readTodos(tx, archivedTodos)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load archive: %w", err)
+	}
+	return todos, nil
+}
+
+// SaveProjects writes the projects.
+func (r *SQLiteRepository) SaveProjects(projects []todo.Project) error {
+	err := r.saveSidecar(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM projects`); err != nil {
+			return err
+		}
+		for i, project := range projects {
+			_, err := tx.Exec(
+				`INSERT INTO projects (name, position, archived, created_at, archived_at) VALUES (?, ?, ?, ?, ?)`,
+				project.Name, i, project.Archived, formatSQLTime(project.CreatedAt), optionalSQLTime(project.ArchivedAt),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save projects: %w", err)
+	}
+	return nil
+}
+
+// LoadProjects reads the projects.
+func (r *SQLiteRepository) LoadProjects() ([]todo.Project, error) {
+	rows, err := r.db.Query(`SELECT name, archived, created_at, archived_at FROM projects ORDER BY position`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load projects: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	projects := []todo.Project{}
+	for rows.Next() {
+		var project todo.Project
+		var createdAt string
+		var archivedAt sql.NullString
+		if err := rows.Scan(&project.Name, &project.Archived, &createdAt, &archivedAt); err != nil {
+			return nil, fmt.Errorf("failed to load projects: %w", err)
+		}
+		if project.CreatedAt, err = parseSQLTime(createdAt); err != nil {
+			return nil, fmt.Errorf("failed to load projects: %w", err)
+		}
+		if project.ArchivedAt, err = parseOptionalSQLTime(archivedAt); err != nil {
+			return nil, fmt.Errorf("failed to load projects: %w", err)
+		}
+		projects = append(projects, project)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load projects: %w", err)
+	}
+	return projects, nil
+}
+
+// SaveJournal writes the undo journal.
+func (r *SQLiteRepository) SaveJournal(journal todo.Journal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
+	if err := r.saveSidecar(func(tx *sql.Tx) error {
+		return writeMeta(tx, metaJournal, string(data))
+	}); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
+	return nil
+}
+
+// LoadJournal reads the undo journal.
+func (r *SQLiteRepository) LoadJournal() (todo.Journal, error) {
+	var journal todo.Journal
+	var data string
+	err := r.db.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaJournal).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return journal, nil
+	}
+	if err != nil {
+		return todo.Journal{}, fmt.Errorf("failed to load journal: %w", err)
+	}
+	if err := json.Unmarshal([]byte(data), &journal); err != nil {
+		return todo.Journal{}, fmt.Errorf("failed to load journal: %w", err)
+	}
+	return journal, nil
+}
+
+// SaveLastID writes the highest todo ID given.
+func (r *SQLiteRepository) SaveLastID(id int) error {
+	if err := r.saveSidecar(func(tx *sql.Tx) error {
+		return writeMeta(tx, metaLastID, strconv.Itoa(id))
+	}); err != nil {
+		return fmt.Errorf("failed to save last ID: %w", err)
+	}
+	return nil
+}
+
+// LoadLastID reads the highest todo ID given.
+func (r *SQLiteRepository) LoadLastID() (int, error) {
+	var value string
+	err := r.db.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaLastID).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load last ID: %w", err)
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load last ID: %w", err)
+	}
+	return id, nil
+}
+
+// AppendHistory adds changes to the history.
+func (r *SQLiteRepository) AppendHistory(changes []todo.Change) error {
+	err := r.inTx(func(tx *sql.Tx) error {
+		for _, change := range changes {
+			_, err := tx.Exec(
+				`INSERT INTO history (todo_id, action, field, old, new, by, at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				change.TodoID, change.Action, change.Field, change.Old, change.New, change.By, formatSQLTime(change.At),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory reads the history in the order it was recorded.
+func (r *SQLiteRepository) LoadHistory() ([]todo.Change, error) {
+	rows, err := r.db.Query(`SELECT todo_id, action, field, old, new, by, at FROM history ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var changes []todo.Change
+	for rows.Next() {
+		var change todo.Change
+		var at string
+		if err := rows.Scan(&change.TodoID, &change.Action, &change.Field, &change.Old, &change.New, &change.By, &at); err != nil {
+			return nil, fmt.Errorf("failed to load history: %w", err)
+		}
+		if change.At, err = parseSQLTime(at); err != nil {
+			return nil, fmt.Errorf("failed to load history: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+	return changes, nil
+}
+
+// saveTx runs fn in a transaction like inTx, as a save that increments the
+// revision, and returns the new revision. If another process saved since
+// the todos were last read or written, it returns todo.ErrConflict instead.
+func (r *SQLiteRepository) saveTx(fn func(tx *sql.Tx) error) (int64, error) {
+	var saved int64
+	err := r.inTx(func(tx *sql.Tx) error {
+		revision, err := readRevision(tx)
+		if err != nil {
+			return err
+		}
+		if r.tracked && revision != r.revision {
+			return todo.ErrConflict
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		saved = revision + 1
+		return writeMeta(tx, metaRevision, strconv.FormatInt(saved, 10))
+	})
+	return saved, err
+}
+
+// saveSidecar saves the archive, projects, journal or last ID with saveTx,
+// so that their changes are found as conflicts like those of the todos.
+func (r *SQLiteRepository) saveSidecar(fn func(tx *sql.Tx) error) error {
+	saved, err := r.saveTx(fn)
+	if err != nil {
+		return err
+	}
+	if r.tracked {
+		r.revision = saved
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds.
+func (r *SQLiteRepository) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// writeTodo inserts or replaces a todo with its tags, blockers and notes.
+func writeTodo(tx *sql.Tx, t todo.Todo, archived bool) error {
+	var recurrence any
+	if t.Recurrence != nil {
+		recurrence = t.Recurrence.String()
+	}
+
+	dueAt, dueOffset := formatSQLDueTime(t.DueAt)
+	_, err := tx.Exec(`
+		INSERT INTO todos (id, description, completed, priority, created_at, completed_at, due_at, due_offset,
+			project, updated_at, parent_id, recurrence, series_id, deleted_at, archived)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			description = excluded.description, completed = excluded.completed,
+			priority = excluded.priority, created_at = excluded.created_at,
+			completed_at = excluded.completed_at, due_at = excluded.due_at,
+			due_offset = excluded.due_offset, project = excluded.project, updated_at = excluded.updated_at,
+			parent_id = excluded.parent_id, recurrence = excluded.recurrence,
+			series_id = excluded.series_id, deleted_at = excluded.deleted_at,
+			archived = excluded.archived`,
+		t.ID, t.Description, t.Completed, int(t.Priority), formatSQLTime(t.CreatedAt), optionalSQLTime(t.CompletedAt),
+		dueAt, dueOffset, optionalSQLString(t.Project), optionalSQLTime(t.UpdatedAt), optionalSQLInt(t.ParentID),
+		recurrence, optionalSQLInt(t.SeriesID), optionalSQLTime(t.DeletedAt), archived,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range []string{"todo_tags", "todo_blockers", "todo_notes"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE todo_id = ?`, t.ID); err != nil {
+			return err
+		}
+	}
+	for i, tag := range t.Tags {
+		if _, err := tx.Exec(`INSERT INTO todo_tags (todo_id, position, tag) VALUES (?, ?, ?)`, t.ID, i, tag); err != nil {
+			return err
+		}
+	}
+	for i, blocker := range t.BlockedBy {
+		if _, err := tx.Exec(`INSERT INTO todo_blockers (todo_id, position, blocker_id) VALUES (?, ?, ?)`, t.ID, i, blocker); err != nil {
+			return err
+		}
+	}
+	for i, note := range t.Notes {
+		_, err := tx.Exec(
+			`INSERT INTO todo_notes (todo_id, position, text, created_at) VALUES (?, ?, ?, ?)`,
+			t.ID, i, note.Text, formatSQLTime(note.CreatedAt),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Conditions of readTodos.
+const (
+	activeTodos   = `t.archived = 0`
+	archivedTodos = `t.archived = 1`
+)
+
+// listCondition returns the condition of readTodos selecting the active
+// todos opts selects.
+func listCondition(opts todo.ListOptions) (string, []any) {
+	conditions := []string{activeTodos}
+	var args []any
+	if len(opts.Tags) > 0 || opts.DueFrom != nil || opts.DueBefore != nil ||
+		opts.ParentID != 0 || opts.SeriesID != 0 || opts.BlockedBy != 0 || opts.Project != "" {
+		// Nearly all todos are active, so the unary + keeps SQLite from
+		// searching todos_status for them instead of the more selective
+		// indexes on tags, due dates, parents, series, blockers and
+		// projects.
+		conditions[0] = `+` + activeTodos
+	}
+
+	switch opts.Status {
+	case todo.StatusPending:
+		conditions = append(conditions, `t.completed = 0`)
+	case todo.StatusCompleted:
+		conditions = append(conditions, `t.completed = 1`)
+	}
+	switch opts.Trash {
+	case todo.WithoutTrash:
+		conditions = append(conditions, `t.deleted_at IS NULL`)
+	case todo.OnlyTrash:
+		conditions = append(conditions, `t.deleted_at IS NOT NULL`)
+	}
+	for _, tag := range opts.Tags {
+		conditions = append(conditions, `t.id IN (SELECT todo_id FROM todo_tags WHERE tag = ?)`)
+		args = append(args, todo.NormalizeTag(tag))
+	}
+	if opts.DueFrom != nil {
+		conditions = append(conditions, `t.due_at >= ?`)
+		args = append(args, opts.DueFrom.UTC().Format(sqlDueLayout))
+	}
+	if opts.DueBefore != nil {
+		conditions = append(conditions, `t.due_at < ?`)
+		args = append(args, opts.DueBefore.UTC().Format(sqlDueLayout))
+	}
+	if opts.ParentID != 0 {
+		conditions = append(conditions, `t.parent_id = ?`)
+		args = append(args, opts.ParentID)
+	}
+	if opts.SeriesID != 0 {
+		conditions = append(conditions, `t.series_id = ?`)
+		args = append(args, opts.SeriesID)
+	}
+	if opts.BlockedBy != 0 {
+		conditions = append(conditions, `t.id IN (SELECT todo_id FROM todo_blockers WHERE blocker_id = ?)`)
+		args = append(args, opts.BlockedBy)
+	}
+	if opts.Project != "" {
+		conditions = append(conditions, `t.project = ?`)
+		args = append(args, opts.Project)
+	}
+
+	return strings.Join(conditions, ` AND `), args
+}
+
+// listOrder returns the ORDER BY clause ordering the todos table, as t, on
+// the sort keys and then by ID, if SQL orders them the same as the todo
+// package. Other times than due times are not ordered by their text, and
+// descriptions are ordered ignoring case beyond ASCII, so only ID, due
+// time and priority are.
+func listOrder(keys []todo.SortKey) (string, bool) {
+	columns := map[string]string{"id": "t.id", "due": "t.due_at", "priority": "t.priority"}
+
+	terms := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		column, ok := columns[key.Field]
+		if !ok {
+			return "", false
+		}
+		if key.Descending {
+			column += " DESC"
+		}
+		if key.Field == "due" {
+			// Todos without a due time come last either way.
+			column = "t.due_at IS NULL, " + column
+		}
+		terms = append(terms, column)
+	}
+	return strings.Join(append(terms, "t.id"), ", "), true
+}
+
+// readTodos reads the todos matching a condition on the todos table, as t,
+// ordered by ID.
+func readTodos(q queryer, where string, args ...any) ([]todo.Todo, error) {
+	rows, err := q.Query(`
+		SELECT id, description, completed, priority, created_at, completed_at, due_at, due_offset,
+			project, updated_at, parent_id, recurrence, series_id, deleted_at
+		FROM todos t WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	todos := []todo.Todo{}
+	index := make(map[int]int)
+	for rows.Next() {
+		t, err := scanTodo(rows)
+		if err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		index[t.ID] = len(todos)
+		todos = append(todos, t)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Tags, blockers and notes are read with one query each.
+	byTodo := func(query string, scan func(rows *sql.Rows) (int, func(t *todo.Todo), error)) error {
+		rows, err := q.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rows.Close() }()
+
+		for rows.Next() {
+			id, attach, err := scan(rows)
+			if err != nil {
+				return err
+			}
+			if i, ok := index[id]; ok {
+				attach(&todos[i])
+			}
+		}
+		return rows.Err()
+	}
+
+	err = byTodo(`
+		SELECT c.todo_id, c.tag FROM todo_tags c JOIN todos t ON t.id = c.todo_id
+		WHERE `+where+` ORDER BY c.todo_id, c.position`,
+		func(rows *sql.Rows) (int, func(t *todo.Todo), error) {
+			var id int
+			var tag string
+			err := rows.Scan(&id, &tag)
+			return id, func(t *todo.Todo) { t.Tags = append(t.Tags, tag) }, err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = byTodo(`
+		SELECT c.todo_id, c.blocker_id FROM todo_blockers c JOIN todos t ON t.id = c.todo_id
+		WHERE `+where+` ORDER BY c.todo_id, c.position`,
+		func(rows *sql.Rows) (int, func(t *todo.Todo), error) {
+			var id, blocker int
+			err := rows.Scan(&id, &blocker)
+			return id, func(t *todo.Todo) { t.BlockedBy = append(t.BlockedBy, blocker) }, err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = byTodo(`
+		SELECT c.todo_id, c.text, c.created_at FROM todo_notes c JOIN todos t ON t.id = c.todo_id
+		WHERE `+where+` ORDER BY c.todo_id, c.position`,
+		func(rows *sql.Rows) (int, func(t *todo.Todo), error) {
+			var id int
+			var note todo.Note
+			var createdAt string
+			if err := rows.Scan(&id, &note.Text, &createdAt); err != nil {
+				return 0, nil, err
+			}
+			var err error
+			note.CreatedAt, err = parseSQLTime(createdAt)
+			return id, func(t *todo.Todo) { t.Notes = append(t.Notes, note) }, err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// scanTodo reads a row of the todos table, without its tags, blockers and
+// notes.
+func scanTodo(rows *sql.Rows) (todo.Todo, error) {
+	var t todo.Todo
+	var priority int
+	var createdAt string
+	var completedAt, dueAt, project, updatedAt, recurrence, deletedAt sql.NullString
+	var dueOffset, parentID, seriesID sql.NullInt64
+
+	err := rows.Scan(&t.ID, &t.Description, &t.Completed, &priority, &createdAt, &completedAt, &dueAt, &dueOffset,
+		&project, &updatedAt, &parentID, &recurrence, &seriesID, &deletedAt)
+	if err != nil {
+		return todo.Todo{}, err
+	}
+
+	t.Priority = todo.Priority(priority)
+	t.Project = project.String
+	t.ParentID = int(parentID.Int64)
+	t.SeriesID = int(seriesID.Int64)
+	if recurrence.Valid {
+		rule, err := todo.ParseRecurrence(recurrence.String)
+		if err != nil {
+			return todo.Todo{}, fmt.Errorf("todo %d: %w", t.ID, err)
+		}
+		t.Recurrence = &rule
+	}
+
+	if t.CreatedAt, err = parseSQLTime(createdAt); err != nil {
+		return todo.Todo{}, err
+	}
+	if t.DueAt, err = parseSQLDueTime(dueAt, dueOffset); err != nil {
+		return todo.Todo{}, err
+	}
+	for _, field := range []struct {
+		value sql.NullString
+		dest  **time.Time
+	}{
+		{completedAt, &t.CompletedAt},
+		{updatedAt, &t.UpdatedAt},
+		{deletedAt, &t.DeletedAt},
+	} {
+		if *field.dest, err = parseOptionalSQLTime(field.value); err != nil {
+			return todo.Todo{}, err
+		}
+	}
+	return t, nil
+}
+
+// activeIDs returns the IDs of the active todos in the database, mapped to
+// empty todos.
+func activeIDs(tx *sql.Tx) (map[int]todo.Todo, error) {
+	rows, err := tx.Query(`SELECT id FROM todos WHERE archived = 0`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := make(map[int]todo.Todo)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = todo.Todo{}
+	}
+	return ids, rows.Err()
+}
+
+// todoMap indexes copies of todos by ID. The slices are copied, as the
+// service changes some of them in place.
+func todoMap(todos []todo.Todo) map[int]todo.Todo {
+	m := make(map[int]todo.Todo, len(todos))
+	for _, t := range todos {
+		m[t.ID] = cloneTodo(t)
+	}
+	return m
+}
+
+// cloneTodo copies a todo with its slices and recurrence.
+func cloneTodo(t todo.Todo) todo.Todo {
+	t.Tags = slices.Clone(t.Tags)
+	t.BlockedBy = slices.Clone(t.BlockedBy)
+	t.Notes = slices.Clone(t.Notes)
+	if t.Recurrence != nil {
+		rule := *t.Recurrence
+		t.Recurrence = &rule
+	}
+	return t
+}
+
+func readRevision(tx *sql.Tx) (int64, error) {
+	var value string
+	err := tx.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaRevision).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func writeMeta(tx *sql.Tx, key, value string) error {
+	_, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+// Times are stored as RFC 3339 text with their offset, so they read back
+// in the same zone, as with JSON.
+func formatSQLTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func optionalSQLTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatSQLTime(*t)
+}
+
+func parseSQLTime(text string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, text)
+}
+
+func parseOptionalSQLTime(text sql.NullString) (*time.Time, error) {
+	if !text.Valid {
+		return nil, nil
+	}
+	t, err := parseSQLTime(text.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// sqlDueLayout formats due times in UTC with a fixed width, so that their
+// text orders them and ranges of them are selected with the index.
+const sqlDueLayout = "2006-01-02T15:04:05.000000000Z"
+
+// formatSQLDueTime returns the due_at and due_offset of a due time.
+func formatSQLDueTime(t *time.Time) (any, any) {
+	if t == nil {
+		return nil, nil
+	}
+	_, offset := t.Zone()
+	return t.UTC().Format(sqlDueLayout), offset
+}
+
+// parseSQLDueTime reads a due time back in its offset, in UTC or the local
+// zone when that has the same offset, as parseSQLTime does.
+func parseSQLDueTime(text sql.NullString, offset sql.NullInt64) (*time.Time, error) {
+	if !text.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(sqlDueLayout, text.String)
+	if err != nil {
+		return nil, err
+	}
+	if _, local := t.In(time.Local).Zone(); offset.Int64 != 0 && int64(local) == offset.Int64 {
+		t = t.In(time.Local)
+	} else if offset.Int64 != 0 {
+		t = t.In(time.FixedZone("", int(offset.Int64)))
+	}
+	return &t, nil
+}
+
+func optionalSQLString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func optionalSQLInt(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
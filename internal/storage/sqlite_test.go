@@ -0,0 +1,504 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/todo/internal/todo"
+)
+
+func newTestSQLiteRepository(t *testing.T, filename string) *SQLiteRepository {
+	t.Helper()
+
+	repo, err := NewSQLiteRepository(filename)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+func TestSQLiteRepository_SaveAndLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.db")
+	repo := newTestSQLiteRepository(t, filename)
+
+	zone := time.FixedZone("", 2*60*60)
+	created := time.Date(2023, 1, 1, 12, 0, 0, 123, zone)
+	due := time.Date(2023, 1, 5, 0, 0, 0, 0, zone)
+	rule, err := todo.ParseRecurrence("weekly mon,thu")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := []todo.Todo{
+		{
+			ID:          1,
+			Description: "Everything set",
+			Completed:   true,
+			Priority:    todo.PriorityHigh,
+			CreatedAt:   created,
+			CompletedAt: &created,
+			DueAt:       &due,
+			Tags:        []string{"infra", "security"},
+			Project:     "backend",
+			UpdatedAt:   &created,
+			ParentID:    2,
+			BlockedBy:   []int{2, 3},
+			Recurrence:  &rule,
+			SeriesID:    1,
+			Notes:       []todo.Note{{Text: "First", CreatedAt: created}, {Text: "Second", CreatedAt: created}},
+		},
+		{ID: 2, Description: "Nothing set", CreatedAt: created},
+		{ID: 3, Description: "Deleted", CreatedAt: created, DeletedAt: &created},
+	}
+
+	if err := repo.Save(original); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+
+	loaded, err := newTestSQLiteRepository(t, filename).Load()
+	if err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+
+	if !reflect.DeepEqual(loaded, original) {
+		t.Errorf("Expected todos to round-trip\nexpected: %+v\ngot:      %+v", original, loaded)
+	}
+}
+
+func TestSQLiteRepository_Save_OnlyChangedRows(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.db")
+	repo := newTestSQLiteRepository(t, filename)
+
+	created := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	todos := []todo.Todo{
+		{ID: 1, Description: "Keep", CreatedAt: created, Tags: []string{"a"}},
+		{ID: 2, Description: "Change", CreatedAt: created, BlockedBy: []int{1}},
+		{ID: 3, Description: "Remove", CreatedAt: created},
+	}
+	if err := repo.Save(todos); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+
+	// A row written behind the repository's back shows which rows are
+	// rewritten: only changed todos are.
+	if _, err := repo.db.Exec(`UPDATE todos SET description = 'Untouched' WHERE id = 1`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Blockers are removed in place, as Service.Unblock does.
+	todos[1].BlockedBy = todos[1].BlockedBy[:0]
+	todos[1].Description = "Changed"
+	if err := repo.Save(todos[:2]); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+
+	loaded, err := newTestSQLiteRepository(t, filename).Load()
+	if err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+
+	if len(loaded) != 2 {
+		t.Fatalf("Expected 2 todos, got %d", len(loaded))
+	}
+	if loaded[0].Description != "Untouched" {
+		t.Errorf("Expected unchanged todo not to be written, got %q", loaded[0].Description)
+	}
+	if loaded[1].Description != "Changed" || len(loaded[1].BlockedBy) != 0 {
+		t.Errorf("Expected changed todo to be written, got %+v", loaded[1])
+	}
+}
+
+func TestSQLiteRepository_Save_Conflict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.db")
+	first := newTestSQLiteRepository(t, filename)
+	second := newTestSQLiteRepository(t, filename)
+
+	if _, err := first.Load(); err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+	if _, err := second.Load(); err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+
+	if err := first.Save([]todo.Todo{{ID: 1, Description: "First"}}); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+
+	err := second.Save([]todo.Todo{{ID: 1, Description: "Second"}})
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+
+	if _, err := second.Load(); err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+	if err := second.Save([]todo.Todo{{ID: 1, Description: "Second"}}); err != nil {
+		t.Errorf("Unexpected error after reload: %v", err)
+	}
+}
+
+func TestSQLiteRepository_Save_SidecarConflict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.db")
+	first := newTestSQLiteRepository(t, filename)
+	second := newTestSQLiteRepository(t, filename)
+
+	for _, repo := range []*SQLiteRepository{first, second} {
+		if _, err := repo.Load(); err != nil {
+			t.Fatalf("Failed to load todos: %v", err)
+		}
+	}
+
+	if err := first.SaveProjects([]todo.Project{{Name: "home"}}); err != nil {
+		t.Fatalf("Failed to save projects: %v", err)
+	}
+	// Saving the projects counts as a save of the first repository.
+	if err := first.Save([]todo.Todo{{ID: 1, Description: "First"}}); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+
+	err := second.SaveJournal(todo.Journal{})
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Errorf("Expected ErrConflict saving the journal, got %v", err)
+	}
+	err = second.SaveArchive(nil)
+	if !errors.Is(err, todo.ErrConflict) {
+		t.Errorf("Expected ErrConflict saving the archive, got %v", err)
+	}
+	if _, err := second.Load(); err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+	if err := second.SaveProjects([]todo.Project{{Name: "home"}, {Name: "work"}}); err != nil {
+		t.Errorf("Unexpected error after reload: %v", err)
+	}
+}
+
+func TestSQLiteRepository_Service(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.db")
+	service := todo.NewService(newTestSQLiteRepository(t, filename))
+	service.SetActor("alice")
+
+	if _, err := service.AddProject("backend"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, description := range []string{"Archive me", "Delete me", "Keep me"} {
+		if _, err := service.Add(description, todo.WithProject("backend"), todo.WithTags("sprint")); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+	if err := service.Complete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.Archive(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.Delete(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	reloaded := todo.NewService(newTestSQLiteRepository(t, filename))
+
+	if todos, err := reloaded.List(todo.ListOptions{}); err != nil || len(todos) != 1 || todos[0].ID != 3 || todos[0].Tags[0] != "sprint" {
+		t.Errorf("Expected only todo 3 to be active, got %+v (%v)", todos, err)
+	}
+	if trash, err := reloaded.GetTrash(); err != nil || len(trash) != 1 || trash[0].ID != 2 {
+		t.Errorf("Expected todo 2 in the trash, got %+v (%v)", trash, err)
+	}
+	if archived, err := reloaded.GetArchived(); err != nil || len(archived) != 1 || archived[0].ID != 1 {
+		t.Errorf("Expected todo 1 in the archive, got %+v (%v)", archived, err)
+	}
+	if projects := reloaded.GetProjects(); len(projects) != 1 || projects[0].Name != "backend" {
+		t.Errorf("Expected project backend, got %+v", projects)
+	}
+
+	history, err := reloaded.History(2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(history) != 2 || history[1].Field != "deleted" || history[1].By != "alice" {
+		t.Errorf("Expected add and delete in the history, got %+v", history)
+	}
+
+	// The journal is stored too, so the delete can be undone.
+	if _, err := reloaded.Undo(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if todos, err := reloaded.List(todo.ListOptions{}); err != nil || len(todos) != 2 {
+		t.Errorf("Expected 2 active todos after undo, got %+v (%v)", todos, err)
+	}
+
+	// The last ID is stored too, so the ID of a purged todo is not reused.
+	if err := reloaded.Delete(3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := reloaded.PurgeTrash(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	added, err := todo.NewService(newTestSQLiteRepository(t, filename)).Add("New")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if added.ID != 4 {
+		t.Errorf("Expected ID 4, got %d", added.ID)
+	}
+}
+
+func TestSQLiteRepository_Store(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.db")
+	repo := newTestSQLiteRepository(t, filename)
+
+	created := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	for id := 1; id <= 5; id++ {
+		if err := repo.Create(todo.Todo{ID: id, Description: "Todo", CreatedAt: created, Tags: []string{"tag"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := repo.Create(todo.Todo{ID: 1, CreatedAt: created}); err == nil {
+		t.Error("Expected error creating an existing todo, got nil")
+	}
+
+	if err := repo.Update(todo.Todo{ID: 2, Description: "Changed", CreatedAt: created}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, err := repo.Get(2); err != nil || got.Description != "Changed" || len(got.Tags) != 0 {
+		t.Errorf("Expected todo 2 to be changed, got %+v (%v)", got, err)
+	}
+	if _, err := repo.Get(9); !errors.Is(err, todo.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := repo.Delete(9); !errors.Is(err, todo.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	page, err := repo.List(todo.ListOptions{Offset: 1, Limit: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != 2 || page[1].ID != 3 || page[1].Tags[0] != "tag" {
+		t.Errorf("Expected todos 2 and 3, got %+v", page)
+	}
+	tagged, err := repo.List(todo.ListOptions{Tags: []string{"tag"}, Sort: []todo.SortKey{{Field: "id", Descending: true}}, Limit: 2})
+	if err != nil || len(tagged) != 2 || tagged[0].ID != 5 || tagged[1].ID != 4 {
+		t.Errorf("Expected todos 5 and 4, got %+v (%v)", tagged, err)
+	}
+
+	// A failing batch writes nothing.
+	err = repo.Batch(func() error {
+		if err := repo.Delete(1); err != nil {
+			return err
+		}
+		return repo.Delete(9)
+	})
+	if !errors.Is(err, todo.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := repo.Get(1); err != nil {
+		t.Errorf("Expected todo 1 to be kept, got %v", err)
+	}
+
+	// Writes through another connection are detected.
+	other := newTestSQLiteRepository(t, filename)
+	if _, err := other.List(todo.ListOptions{Limit: 1}); err != nil {
+		t.Fatalf("Failed to list todos: %v", err)
+	}
+	if err := other.Delete(5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := repo.Delete(4); !errors.Is(err, todo.ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+}
+
+func TestSQLiteRepository_List_Conflict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.db")
+	first := newTestSQLiteRepository(t, filename)
+	second := newTestSQLiteRepository(t, filename)
+
+	if err := first.Create(todo.Todo{ID: 1, Description: "First"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := second.List(todo.ListOptions{}); err != nil {
+		t.Fatalf("Failed to list todos: %v", err)
+	}
+	if err := first.Create(todo.Todo{ID: 2, Description: "Second"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The first write after listing finds the change made since.
+	if err := second.Create(todo.Todo{ID: 3, Description: "Third"}); !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	if _, err := second.Get(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := second.Create(todo.Todo{ID: 3, Description: "Third"}); err != nil {
+		t.Fatalf("Unexpected error after reading again: %v", err)
+	}
+
+	// Having read only some of the todos, Save removes all the others.
+	if _, err := second.List(todo.ListOptions{Limit: 1}); err != nil {
+		t.Fatalf("Failed to list todos: %v", err)
+	}
+	if err := second.Save([]todo.Todo{{ID: 1, Description: "Only"}}); err != nil {
+		t.Fatalf("Failed to save todos: %v", err)
+	}
+	loaded, err := first.Load()
+	if err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Description != "Only" {
+		t.Errorf("Expected only the saved todo, got %+v", loaded)
+	}
+}
+
+func TestSQLiteRepository_List(t *testing.T) {
+	repo := newTestSQLiteRepository(t, filepath.Join(t.TempDir(), "todos.db"))
+
+	// Due times in far apart zones fall on other dates in UTC.
+	east, west := time.FixedZone("", 14*60*60), time.FixedZone("", -12*60*60)
+	at := func(day, hour int, zone *time.Location) *time.Time {
+		date := time.Date(2024, 3, day, hour, 0, 0, 0, zone)
+		return &date
+	}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	todos := []todo.Todo{
+		{ID: 1, Description: "a", Priority: todo.PriorityHigh, DueAt: at(10, 1, east), Tags: []string{"work"}},
+		{ID: 2, Description: "b", Completed: true, CompletedAt: at(1, 0, time.UTC), DueAt: at(9, 23, west), ParentID: 1, SeriesID: 2, Project: "backend"},
+		{ID: 3, Description: "c", Priority: todo.PriorityLow, DueAt: at(9, 12, time.UTC), Tags: []string{"work", "home"}, ParentID: 1, BlockedBy: []int{4}, Project: "backend"},
+		{ID: 4, Description: "d", Priority: todo.PriorityHigh, Tags: []string{"home"}},
+		{ID: 5, Description: "e", DeletedAt: at(2, 0, time.UTC), DueAt: at(9, 0, time.UTC), Tags: []string{"work"}, BlockedBy: []int{2, 4}},
+	}
+	for _, item := range todos {
+		item.CreatedAt = created
+		if err := repo.Create(item); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	all, err := repo.Load()
+	if err != nil {
+		t.Fatalf("Failed to load todos: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts todo.ListOptions
+	}{
+		{"all", todo.ListOptions{}},
+		{"pending", todo.ListOptions{Status: todo.StatusPending}},
+		{"completed", todo.ListOptions{Status: todo.StatusCompleted}},
+		{"without trash", todo.ListOptions{Trash: todo.WithoutTrash}},
+		{"only trash", todo.ListOptions{Trash: todo.OnlyTrash}},
+		{"tags", todo.ListOptions{Tags: []string{"+Work", "home"}}},
+		{"due before", todo.ListOptions{DueBefore: at(9, 12, time.UTC)}},
+		{"due range across zones", todo.ListOptions{DueFrom: at(9, 11, time.UTC), DueBefore: at(9, 12, west)}},
+		{"parent", todo.ListOptions{ParentID: 1}},
+		{"series", todo.ListOptions{SeriesID: 2}},
+		{"blocked by", todo.ListOptions{BlockedBy: 4}},
+		{"project", todo.ListOptions{Project: "backend", Status: todo.StatusPending}},
+		{"sorted by priority and paged", todo.ListOptions{Sort: []todo.SortKey{{Field: "priority", Descending: true}}, Offset: 1, Limit: 2}},
+		{"sorted by due and paged", todo.ListOptions{Sort: []todo.SortKey{{Field: "due"}}, Limit: 3}},
+		{"sorted by due descending", todo.ListOptions{Sort: []todo.SortKey{{Field: "due", Descending: true}}}},
+		{"sorted by description", todo.ListOptions{Sort: []todo.SortKey{{Field: "description"}}, Offset: 2}},
+		{"selected, sorted and paged", todo.ListOptions{Status: todo.StatusPending, Trash: todo.WithoutTrash, Tags: []string{"work"}, Sort: []todo.SortKey{{Field: "priority"}}, Offset: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, err := tt.opts.Apply(all)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got, err := repo.List(tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(got) != len(expected) {
+				t.Fatalf("Expected %d todos, got %d: %+v", len(expected), len(got), got)
+			}
+			for i := range expected {
+				if !reflect.DeepEqual(got[i], expected[i]) {
+					t.Errorf("Position %d: expected %+v, got %+v", i, expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteRepository_MigratesDueTimes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todos.db")
+	repo := newTestSQLiteRepository(t, filename)
+
+	// Earlier versions stored due times as RFC 3339 text with their offset.
+	zone := time.FixedZone("", -5*60*60)
+	due := time.Date(2024, 3, 9, 23, 0, 0, 0, zone)
+	if err := repo.Create(todo.Todo{ID: 1, Description: "Old", CreatedAt: due, DueAt: &due}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, statement := range []string{
+		`UPDATE todos SET due_at = '` + due.Format(time.RFC3339Nano) + `', due_offset = NULL`,
+		`PRAGMA user_version = 0`,
+	} {
+		if _, err := repo.db.Exec(statement); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	_ = repo.Close()
+
+	migrated := newTestSQLiteRepository(t, filename)
+	from := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	todos, err := migrated.List(todo.ListOptions{DueFrom: &from})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(todos) != 1 || !todos[0].DueAt.Equal(due) || todos[0].DueAt.Format(time.RFC3339) != due.Format(time.RFC3339) {
+		t.Errorf("Expected the todo due at %v, got %+v", due, todos)
+	}
+}
+
+func TestSQLiteRepository_List_UsesIndexes(t *testing.T) {
+	repo := newTestSQLiteRepository(t, filepath.Join(t.TempDir(), "todos.db"))
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		opts  todo.ListOptions
+		index string
+	}{
+		{"status", todo.ListOptions{Status: todo.StatusPending}, "todos_status"},
+		{"tag", todo.ListOptions{Tags: []string{"work"}}, "todo_tags_tag"},
+		{"due", todo.ListOptions{DueBefore: &now}, "todos_due"},
+		{"overdue", todo.ListOptions{Status: todo.StatusPending, Trash: todo.WithoutTrash, DueBefore: &now}, "todos_due"},
+		{"parent", todo.ListOptions{Trash: todo.WithoutTrash, ParentID: 1}, "todos_parent"},
+		{"series", todo.ListOptions{Status: todo.StatusPending, SeriesID: 1}, "todos_series"},
+		{"blocked by", todo.ListOptions{BlockedBy: 1}, "todo_blockers_blocker"},
+		{"project", todo.ListOptions{Project: "backend"}, "todos_project"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := listCondition(tt.opts)
+			rows, err := repo.db.Query(`EXPLAIN QUERY PLAN SELECT id FROM todos t WHERE `+where, args...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer func() { _ = rows.Close() }()
+
+			var plan []string
+			for rows.Next() {
+				var id, parent, unused int
+				var detail string
+				if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				plan = append(plan, detail)
+			}
+			if !strings.Contains(strings.Join(plan, "\n"), tt.index) {
+				t.Errorf("Expected the plan to use %s, got %q", tt.index, plan)
+			}
+		})
+	}
+}
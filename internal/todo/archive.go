@@ -0,0 +1,147 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ArchiveRepository is implemented by repositories that can store archived
+// todos apart from the active ones, so that saving the active todos does
+// not rewrite the whole history.
+type ArchiveRepository interface {
+	SaveArchive(todos []Todo) error
+	LoadArchive() ([]Todo, error)
+}
+
+// Archive moves the todos completed before the given time out of the active
+// todos into the archive and returns them. A todo is only archived together
+// with all of its subtasks, so todos with subtasks that are still open or
+// were completed later stay active.
+func (s *Service) Archive(before time.Time) ([]Todo, error) {
+	var moved []Todo
+	err := s.retry(func() error {
+		var err error
+		moved, err = s.archive(before)
+		return err
+	})
+	return moved, err
+}
+
+// archive is Archive without retrying on conflicts.
+func (s *Service) archive(before time.Time) ([]Todo, error) {
+	repo, ok := s.repo.(ArchiveRepository)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support archiving")
+	}
+
+	completed, err := s.list(ListOptions{Status: StatusCompleted, Trash: WithoutTrash})
+	if err != nil {
+		return nil, err
+	}
+
+	eligible := make(map[int]bool)
+	for _, todo := range completed {
+		if todo.CompletedAt != nil && todo.CompletedAt.Before(before) {
+			eligible[todo.ID] = true
+		}
+	}
+	for id := range eligible {
+		descendants, err := s.GetDescendants(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, descendant := range descendants {
+			if !eligible[descendant.ID] {
+				delete(eligible, id)
+				break
+			}
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, nil
+	}
+
+	archived, err := s.GetArchived()
+	if err != nil {
+		return nil, err
+	}
+
+	var moved []Todo
+	for _, todo := range completed {
+		if eligible[todo.ID] {
+			s.markChanged(*todo)
+			moved = append(moved, *todo)
+			s.todos[todo.ID] = nil
+		}
+	}
+
+	// Replace rather than duplicate todos left in the archive by an earlier
+	// run that failed to save the active todos.
+	archived = withoutTodos(archived, eligible)
+	archived = append(archived, moved...)
+	sort.SliceStable(archived, func(i, j int) bool {
+		return archived[i].ID < archived[j].ID
+	})
+
+	if err := repo.SaveArchive(archived); err != nil {
+		return nil, err
+	}
+	s.archived = archived
+
+	s.forgetJournal(eligible)
+	if err := s.save(ActionArchive); err != nil {
+		return nil, fmt.Errorf("failed to save todos: %w", err)
+	}
+
+	return moved, nil
+}
+
+// GetArchived returns the archived todos. The archive is only read when it
+// is first needed.
+func (s *Service) GetArchived() ([]Todo, error) {
+	if s.archiveLoaded {
+		return s.archived, nil
+	}
+
+	repo, ok := s.repo.(ArchiveRepository)
+	if !ok {
+		s.archiveLoaded = true
+		return nil, nil
+	}
+
+	archived, err := repo.LoadArchive()
+	if err != nil {
+		return nil, err
+	}
+
+	s.archived = archived
+	s.archiveLoaded = true
+	return archived, nil
+}
+
+// GetStatsIncludingArchive returns statistics about the active and archived
+// todos together.
+func (s *Service) GetStatsIncludingArchive() (Stats, error) {
+	todos, err := s.List(ListOptions{})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	archived, err := s.GetArchived()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return computeStats(append(todos, archived...), time.Now()), nil
+}
+
+func withoutTodos(todos []Todo, ids map[int]bool) []Todo {
+	remaining := make([]Todo, 0, len(todos))
+	for _, todo := range todos {
+		if !ids[todo.ID] {
+			remaining = append(remaining, todo)
+		}
+	}
+	return remaining
+}
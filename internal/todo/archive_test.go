@@ -0,0 +1,170 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+// MockArchiveRepository is a MockRepository that also stores an archive.
+type MockArchiveRepository struct {
+	MockRepository
+	archive []Todo
+	loads   int
+}
+
+func (m *MockArchiveRepository) SaveArchive(todos []Todo) error {
+	m.archive = make([]Todo, len(todos))
+	copy(m.archive, todos)
+	return nil
+}
+
+func (m *MockArchiveRepository) LoadArchive() ([]Todo, error) {
+	m.loads++
+	result := make([]Todo, len(m.archive))
+	copy(result, m.archive)
+	return result, nil
+}
+
+func TestService_Archive(t *testing.T) {
+	repo := &MockArchiveRepository{}
+	service := NewService(repo)
+
+	// 1: completed long ago, 2: completed today, 3: pending,
+	// 4: completed long ago with an open subtask 5.
+	for _, description := range []string{"Old", "New", "Pending", "Parent"} {
+		if _, err := service.Add(description); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+	if _, err := service.Add("Open subtask", WithParent(4)); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	for _, id := range []int{1, 2, 4} {
+		if err := service.Complete(id, CompleteForce()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	longAgo := time.Now().AddDate(0, -2, 0)
+	for i := range repo.todos {
+		if id := repo.todos[i].ID; id == 1 || id == 4 {
+			repo.todos[i].CompletedAt = &longAgo
+		}
+	}
+
+	archived, err := service.Archive(time.Now().AddDate(0, -1, 0))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(archived) != 1 || archived[0].ID != 1 {
+		t.Fatalf("Expected todo 1 to be archived, got %v", archived)
+	}
+	if _, err := service.Get(1); err == nil {
+		t.Error("Expected archived todo to leave the active todos")
+	}
+	if len(repo.archive) != 1 || len(repo.todos) != 4 {
+		t.Errorf("Expected 1 archived and 4 active todos, got %d and %d", len(repo.archive), len(repo.todos))
+	}
+
+	// Archiving everything completed adds to the archive.
+	if _, err := service.Archive(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(repo.archive) != 2 {
+		t.Errorf("Expected 2 archived todos, got %d", len(repo.archive))
+	}
+
+	stats, err := service.GetStatsIncludingArchive()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats.Total != 5 || stats.Completed != 3 {
+		t.Errorf("Expected 5 todos with 3 completed, got %d and %d", stats.Total, stats.Completed)
+	}
+	if active, err := service.GetStats(); err != nil || active.Total != 3 {
+		t.Errorf("Expected 3 active todos, got %d", active.Total)
+	}
+}
+
+func TestService_Archive_ReservesIDs(t *testing.T) {
+	repo := &MockArchiveRepository{}
+	service := NewService(repo)
+	if _, err := service.Add("Archive me"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if err := service.Complete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.Archive(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The archive is not read until a todo is created.
+	reloaded := NewService(repo)
+	loads := repo.loads
+	listTodos(t, reloaded)
+	if repo.loads != loads {
+		t.Error("Expected the archive not to be read when listing todos")
+	}
+
+	added, err := reloaded.Add("New todo")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if added.ID != 2 {
+		t.Errorf("Expected ID 2, got %d", added.ID)
+	}
+}
+
+// MockArchiveIDRepository is a MockArchiveRepository that also keeps the
+// last ID.
+type MockArchiveIDRepository struct {
+	MockArchiveRepository
+	lastID int
+}
+
+func (m *MockArchiveIDRepository) SaveLastID(id int) error {
+	m.lastID = id
+	return nil
+}
+
+func (m *MockArchiveIDRepository) LoadLastID() (int, error) {
+	return m.lastID, nil
+}
+
+func TestService_Archive_LastIDCoversArchive(t *testing.T) {
+	// The archive holds todo 5 from before the last ID was stored.
+	completedAt := time.Now()
+	repo := &MockArchiveIDRepository{}
+	repo.archive = []Todo{{ID: 5, Description: "Archived", Completed: true, CompletedAt: &completedAt}}
+
+	service := NewService(repo)
+	added, err := service.Add("First")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if added.ID != 6 || repo.lastID != 6 {
+		t.Errorf("Expected ID 6 and last ID 6, got %d and %d", added.ID, repo.lastID)
+	}
+
+	// Once the last ID is stored, adding does not read the archive.
+	reloaded := NewService(repo)
+	loads := repo.loads
+	added, err = reloaded.Add("Second")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if added.ID != 7 {
+		t.Errorf("Expected ID 7, got %d", added.ID)
+	}
+	if repo.loads != loads {
+		t.Error("Expected the archive not to be read when adding a todo")
+	}
+}
+
+func TestService_Archive_Unsupported(t *testing.T) {
+	service := NewService(&MockRepository{})
+
+	if _, err := service.Archive(time.Now()); err == nil {
+		t.Error("Expected error when storage does not support archiving, got nil")
+	}
+}
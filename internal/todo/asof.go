@@ -0,0 +1,58 @@
+package todo
+
+import (
+	"fmt"
+	"time"
+)
+
+// AsOfRepository is implemented by repositories that keep every change, so
+// that the todos can be read as they were at an earlier time.
+type AsOfRepository interface {
+	LoadAsOf(at time.Time) ([]Todo, error)
+}
+
+// CompactRepository is implemented by repositories that can fold the
+// changes they keep into the current todos, to save space and loading time.
+// Compact returns how many changes it folded.
+type CompactRepository interface {
+	Compact() (int, error)
+}
+
+// GetAllAsOf returns the todos as they were at the given time, without the
+// ones that were in the trash then.
+func (s *Service) GetAllAsOf(at time.Time) ([]Todo, error) {
+	repo, ok := s.repo.(AsOfRepository)
+	if !ok {
+		return nil, fmt.Errorf("storage does not keep earlier versions of the todos")
+	}
+
+	stored, err := repo.LoadAsOf(at)
+	if err != nil {
+		return nil, err
+	}
+
+	todos := make([]Todo, 0, len(stored))
+	for _, todo := range stored {
+		if todo.DeletedAt == nil {
+			todos = append(todos, todo)
+		}
+	}
+	return todos, nil
+}
+
+// Compact folds the changes kept by the storage into the current todos and
+// returns how many it folded. Earlier versions of the todos can no longer
+// be read afterwards.
+func (s *Service) Compact() (int, error) {
+	repo, ok := s.repo.(CompactRepository)
+	if !ok {
+		return 0, fmt.Errorf("storage does not support compaction")
+	}
+	var compacted int
+	err := s.locked(func() error {
+		var err error
+		compacted, err = repo.Compact()
+		return err
+	})
+	return compacted, err
+}
@@ -0,0 +1,61 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+// MockAsOfRepository is a MockRepository that returns fixed earlier todos
+// and counts compactions.
+type MockAsOfRepository struct {
+	MockRepository
+	earlier     []Todo
+	compactions int
+}
+
+func (m *MockAsOfRepository) LoadAsOf(_ time.Time) ([]Todo, error) {
+	return m.earlier, nil
+}
+
+func (m *MockAsOfRepository) Compact() (int, error) {
+	m.compactions++
+	return 3, nil
+}
+
+func TestService_GetAllAsOf(t *testing.T) {
+	deleted := time.Now()
+	repo := &MockAsOfRepository{earlier: []Todo{
+		{ID: 1, Description: "Active"},
+		{ID: 2, Description: "In the trash", DeletedAt: &deleted},
+	}}
+	service := NewService(repo)
+
+	todos, err := service.GetAllAsOf(deleted)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(todos) != 1 || todos[0].ID != 1 {
+		t.Errorf("Expected only todo 1, got %+v", todos)
+	}
+
+	if _, err := NewService(&MockRepository{}).GetAllAsOf(deleted); err == nil {
+		t.Error("Expected error for storage without earlier versions, got nil")
+	}
+}
+
+func TestService_Compact(t *testing.T) {
+	repo := &MockAsOfRepository{}
+	service := NewService(repo)
+
+	compacted, err := service.Compact()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if compacted != 3 || repo.compactions != 1 {
+		t.Errorf("Expected 3 events in 1 compaction, got %d in %d", compacted, repo.compactions)
+	}
+
+	if _, err := NewService(&MockRepository{}).Compact(); err == nil {
+		t.Error("Expected error for storage without compaction, got nil")
+	}
+}
@@ -0,0 +1,124 @@
+package todo
+
+import "fmt"
+
+// CompleteAll completes several todos as one change that is saved once:
+// either all of them are completed or, if any cannot be, none is. Todos are
+// completed after their subtasks and blockers in the same list, so those do
+// not need CompleteForce. See Complete for the options.
+func (s *Service) CompleteAll(ids []int, opts ...CompleteOption) error {
+	var config completeConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	completed := func(id int) bool {
+		todo, err := s.get(id)
+		return err == nil && todo.Completed
+	}
+	return s.applyAll(ActionComplete, ids, completed, func(id int) error {
+		return s.complete(id, config)
+	})
+}
+
+// IncompleteAll marks several todos as not completed as one change that is
+// saved once: either all of them are changed or none is.
+func (s *Service) IncompleteAll(ids []int) error {
+	incomplete := func(id int) bool {
+		todo, err := s.get(id)
+		return err == nil && !todo.Completed
+	}
+	return s.applyAll(ActionIncomplete, ids, incomplete, s.incomplete)
+}
+
+// DeleteAll moves several todos to the trash as one change that is saved
+// once: either all of them are deleted or none is. See Delete for the
+// options.
+func (s *Service) DeleteAll(ids []int, opts ...DeleteOption) error {
+	var config deleteConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	deleted := func(id int) bool {
+		_, err := s.get(id)
+		return err != nil
+	}
+	return s.applyAll(ActionDelete, ids, deleted, func(id int) error {
+		return s.delete(id, config)
+	})
+}
+
+// applyAll applies a change to each todo and commits them together. done
+// reports whether a todo is already in the state apply puts it in, so that
+// todos changed along with an earlier one, such as subtasks completed by a
+// cascade, are skipped. Todos that cannot be changed yet are retried after
+// the others, and if some still fail all changes are rolled back.
+func (s *Service) applyAll(action string, ids []int, done func(id int) bool, apply func(id int) error) error {
+	return s.retry(func() error {
+		return s.applyAllOnce(action, ids, done, apply)
+	})
+}
+
+// applyAllOnce is applyAll without retrying on conflicts.
+func (s *Service) applyAllOnce(action string, ids []int, done func(id int) bool, apply func(id int) error) error {
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 {
+		return fmt.Errorf("no todos selected")
+	}
+
+	unchanged := func(err error) error {
+		if len(ids) > 1 {
+			return fmt.Errorf("%w; no todos were changed", err)
+		}
+		return err
+	}
+
+	// A todo already in the target state makes apply report why, e.g.
+	// that it is already completed, without changing anything.
+	for _, id := range ids {
+		if done(id) {
+			return unchanged(apply(id))
+		}
+	}
+
+	nextID := s.nextID
+	pending := ids
+	for len(pending) > 0 {
+		var failed []int
+		var firstErr error
+		for _, id := range pending {
+			if done(id) {
+				continue
+			}
+			if err := apply(id); err != nil {
+				failed = append(failed, id)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+
+		if len(failed) == len(pending) {
+			s.revertChanges()
+			s.nextID = nextID
+			return unchanged(firstErr)
+		}
+		pending = failed
+	}
+
+	return s.commit(action, ids...)
+}
+
+// uniqueIDs returns ids without duplicates, in their original order.
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	var unique []int
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
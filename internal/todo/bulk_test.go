@@ -0,0 +1,151 @@
+package todo
+
+import "testing"
+
+// MockCountingRepository is a MockRepository that counts saves.
+type MockCountingRepository struct {
+	MockRepository
+	saves int
+}
+
+func (m *MockCountingRepository) Save(todos []Todo) error {
+	m.saves++
+	return m.MockRepository.Save(todos)
+}
+
+// newBulkService returns a service with todos 1-5, where 2 is a subtask of
+// 1, 4 is blocked by 3 and 5 is completed.
+func newBulkService(t *testing.T) (*Service, *MockCountingRepository) {
+	t.Helper()
+
+	repo := &MockCountingRepository{}
+	service := NewService(repo)
+	for _, description := range []string{"Parent", "Subtask", "Blocker", "Blocked", "Done"} {
+		if _, err := service.Add(description); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+	parentID := 1
+	if _, err := service.Update(2, Update{ParentID: &parentID}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.Block(4, 3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.Complete(5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return service, repo
+}
+
+func TestService_CompleteAll(t *testing.T) {
+	tests := []struct {
+		name        string
+		ids         []int
+		opts        []CompleteOption
+		expectError bool
+		completed   []int
+	}{
+		{"subtasks and blockers first", []int{1, 4, 2, 3}, nil, false, []int{1, 2, 3, 4}},
+		{"duplicates", []int{3, 3}, nil, false, []int{3}},
+		{"cascade covers listed subtask", []int{1, 2}, []CompleteOption{CompleteCascade()}, false, []int{1, 2}},
+		{"open subtask not listed", []int{1, 3}, nil, true, nil},
+		{"already completed", []int{3, 5}, nil, true, nil},
+		{"not found", []int{3, 999}, nil, true, nil},
+		{"empty", nil, nil, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo := newBulkService(t)
+			saves := repo.saves
+
+			err := service.CompleteAll(tt.ids, tt.opts...)
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				if repo.saves != saves {
+					t.Errorf("Expected no save, got %d", repo.saves-saves)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if repo.saves != saves+1 {
+					t.Errorf("Expected 1 save, got %d", repo.saves-saves)
+				}
+			}
+
+			expected := map[int]bool{5: true}
+			for _, id := range tt.completed {
+				expected[id] = true
+			}
+			for _, todo := range listTodos(t, service) {
+				if todo.Completed != expected[todo.ID] {
+					t.Errorf("Todo %d: expected completed %v, got %v", todo.ID, expected[todo.ID], todo.Completed)
+				}
+			}
+		})
+	}
+}
+
+func TestService_IncompleteAll(t *testing.T) {
+	service, _ := newBulkService(t)
+	if err := service.CompleteAll([]int{3, 4}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Todo 1 is not completed, so nothing changes.
+	if err := service.IncompleteAll([]int{3, 1}); err == nil {
+		t.Fatal("Expected error for a todo that is not completed, got nil")
+	}
+	if todo, _ := service.Get(3); !todo.Completed {
+		t.Error("Expected todo 3 to stay completed")
+	}
+
+	if err := service.IncompleteAll([]int{3, 4, 5}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if completed, err := service.List(ListOptions{Status: StatusCompleted}); err != nil || len(completed) != 0 {
+		t.Errorf("Expected no completed todos, got %v (%v)", completed, err)
+	}
+}
+
+func TestService_DeleteAll(t *testing.T) {
+	service, repo := newBulkService(t)
+	saves := repo.saves
+
+	// A cascade already deleted the listed subtask.
+	if err := service.DeleteAll([]int{1, 2, 3}, DeleteCascade()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if repo.saves != saves+1 {
+		t.Errorf("Expected 1 save, got %d", repo.saves-saves)
+	}
+	if len(listTodos(t, service)) != 2 || len(trashTodos(t, service)) != 3 {
+		t.Fatalf("Expected 2 todos and 3 in the trash, got %d and %d", len(listTodos(t, service)), len(trashTodos(t, service)))
+	}
+	if blocked, _ := service.Get(4); len(blocked.BlockedBy) != 0 {
+		t.Errorf("Expected todo 4 to be unblocked, got %v", blocked.BlockedBy)
+	}
+
+	if err := service.DeleteAll([]int{4, 1}); err == nil {
+		t.Fatal("Expected error for a deleted todo, got nil")
+	}
+	if len(listTodos(t, service)) != 2 {
+		t.Errorf("Expected todo 4 not to be deleted, got %d todos", len(listTodos(t, service)))
+	}
+
+	// The whole operation is undone at once.
+	entries, err := service.Undo(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entries[0].String() != "delete #1, #2, #3" {
+		t.Errorf("Expected %q, got %q", "delete #1, #2, #3", entries[0].String())
+	}
+	if len(listTodos(t, service)) != 5 {
+		t.Errorf("Expected 5 todos after undo, got %d", len(listTodos(t, service)))
+	}
+}
@@ -0,0 +1,96 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxConflictRetries bounds how often a mutation is run again on the
+// reloaded todos after a conflict.
+const maxConflictRetries = 3
+
+// ErrConflict is wrapped by the errors of Repository.Save and of mutations
+// when the stored todos were changed, e.g. by another process, since they
+// were loaded.
+var ErrConflict = errors.New("todos were changed by another process since they were loaded")
+
+// Locker is implemented by repositories that can lock the stored todos
+// against other processes. Lock waits up to timeout for other processes to
+// release the lock and returns a function that releases it.
+type Locker interface {
+	Lock(timeout time.Duration) (func() error, error)
+}
+
+// SetLockTimeout sets how long mutations wait for other processes to
+// release the store's lock.
+func (s *Service) SetLockTimeout(timeout time.Duration) {
+	s.lockTimeout = timeout
+}
+
+// locked runs fn holding the repository's lock, if it has one, so that
+// other processes cannot save the todos while fn loads, changes and saves
+// them. Reading needs no lock, as saving replaces or appends to the files
+// without leaving them half written.
+func (s *Service) locked(fn func() error) (err error) {
+	locker, ok := s.repo.(Locker)
+	if !ok || s.holdsLock {
+		return fn()
+	}
+
+	unlock, err := locker.Lock(s.lockTimeout)
+	if err != nil {
+		return err
+	}
+	s.holdsLock = true
+	defer func() {
+		s.holdsLock = false
+		if unlockErr := unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	return fn()
+}
+
+// begin starts a mutation on the todos as they are stored now. It drops
+// the todos an earlier attempt read and the changes it could not save, and
+// loads the last ID, the projects and the journal again.
+func (s *Service) begin() error {
+	s.todos = make(map[int]*Todo)
+	s.changed = nil
+	s.nextID = 0
+	s.archived, s.archiveLoaded = nil, false
+
+	if err := s.loadLastID(); err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+	if err := s.loadSidecars(); err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+	return nil
+}
+
+// retry runs mutate, which reads and changes the todos through the service
+// and saves them, and when saving fails with ErrConflict before anything
+// was written, runs it again on the todos as stored then. Every mutation of
+// the service retries this way, so mutate must have no side effects other
+// than saving. The store stays locked until mutate is done, so once the
+// todos were read again, saving them does not conflict again.
+func (s *Service) retry(mutate func() error) error {
+	return s.locked(func() error {
+		defer func() {
+			s.todos = nil
+		}()
+		for attempt := 0; ; attempt++ {
+			if err := s.begin(); err != nil {
+				return err
+			}
+			writes := s.writes
+			err := mutate()
+			if !errors.Is(err, ErrConflict) || s.writes != writes || attempt == maxConflictRetries {
+				return err
+			}
+		}
+	})
+}
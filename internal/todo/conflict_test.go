@@ -0,0 +1,187 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// mockStore is storage shared by several MockRevisionRepository instances,
+// like a file shared by several processes.
+type mockStore struct {
+	todos    []Todo
+	revision int
+}
+
+// MockRevisionRepository rejects saves when the store changed since it last
+// loaded or saved it. With interfere set, another process saves just before
+// each of its saves.
+type MockRevisionRepository struct {
+	store     *mockStore
+	revision  int
+	interfere bool
+	loads     int
+	saves     int
+}
+
+func (m *MockRevisionRepository) Save(todos []Todo) error {
+	m.saves++
+	if m.interfere {
+		m.store.revision++
+	}
+	if m.revision != m.store.revision {
+		return fmt.Errorf("failed to save todos: %w", ErrConflict)
+	}
+	m.store.todos = cloneTodos(todos)
+	m.store.revision++
+	m.revision = m.store.revision
+	return nil
+}
+
+func (m *MockRevisionRepository) Load() ([]Todo, error) {
+	m.loads++
+	m.revision = m.store.revision
+	return cloneTodos(m.store.todos), nil
+}
+
+// MockConflictingJournalRepository saves todos but finds a conflict when
+// saving the journal.
+type MockConflictingJournalRepository struct {
+	MockCountingRepository
+}
+
+func (m *MockConflictingJournalRepository) SaveJournal(_ Journal) error {
+	return fmt.Errorf("failed to save journal: %w", ErrConflict)
+}
+
+func (m *MockConflictingJournalRepository) LoadJournal() (Journal, error) {
+	return Journal{}, nil
+}
+
+func TestService_Conflict_Retries(t *testing.T) {
+	store := &mockStore{}
+	first := NewService(&MockRevisionRepository{store: store})
+	second := NewService(&MockRevisionRepository{store: store})
+
+	if _, err := first.Add("From first"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	// The second service loaded before the first one saved, so it reloads
+	// and adds its todo after the first one.
+	added, err := second.Add("From second")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if added.ID != 2 {
+		t.Errorf("Expected ID 2, got %d", added.ID)
+	}
+
+	if len(store.todos) != 2 {
+		t.Fatalf("Expected 2 todos, got %d", len(store.todos))
+	}
+	for i, expected := range []string{"From first", "From second"} {
+		if store.todos[i].ID != i+1 || store.todos[i].Description != expected {
+			t.Errorf("Position %d: expected #%d %q, got #%d %q", i, i+1, expected, store.todos[i].ID, store.todos[i].Description)
+		}
+	}
+
+	// Undo only reverts this service's own change.
+	if _, err := second.Undo(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(store.todos) != 1 || store.todos[0].Description != "From first" {
+		t.Errorf("Expected only the first todo, got %v", store.todos)
+	}
+}
+
+func TestService_Conflict_AppliesToReloadedTodos(t *testing.T) {
+	store := &mockStore{}
+	first := NewService(&MockRevisionRepository{store: store})
+	if _, err := first.Add("Shared"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	second := NewService(&MockRevisionRepository{store: store})
+
+	description := "Renamed"
+	if _, err := first.Update(1, Update{Description: &description}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := second.Complete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(store.todos) != 1 || store.todos[0].Description != "Renamed" || !store.todos[0].Completed {
+		t.Errorf("Expected todo 1 renamed and completed, got %+v", store.todos)
+	}
+}
+
+func TestService_Conflict_GivesUp(t *testing.T) {
+	store := &mockStore{}
+	repo := &MockRevisionRepository{store: store, interfere: true}
+	service := NewService(repo)
+
+	_, err := service.Add("Never saved")
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	if repo.saves != maxConflictRetries+1 {
+		t.Errorf("Expected %d attempts, got %d", maxConflictRetries+1, repo.saves)
+	}
+	if len(store.todos) != 0 {
+		t.Errorf("Expected no stored todos, got %d", len(store.todos))
+	}
+}
+
+func TestService_Conflict_OtherErrors(t *testing.T) {
+	repo := &MockRevisionRepository{store: &mockStore{}}
+	service := NewService(repo)
+
+	if err := service.Complete(999); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if repo.loads != 1 {
+		t.Errorf("Expected no reload, got %d loads", repo.loads)
+	}
+}
+
+func TestService_Conflict_AfterWrite(t *testing.T) {
+	repo := &MockConflictingJournalRepository{}
+	service := NewService(repo)
+
+	// The todo is saved before the journal conflicts, so adding it again
+	// would add it twice.
+	if _, err := service.Add("Saved once"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	if repo.saves != 1 || len(repo.todos) != 1 {
+		t.Errorf("Expected 1 save of 1 todo, got %d saves of %d todos", repo.saves, len(repo.todos))
+	}
+}
+
+func TestService_Conflict_ReloadsAfterList(t *testing.T) {
+	store := &mockStore{}
+	first := NewService(&MockRevisionRepository{store: store})
+	second := NewService(&MockRevisionRepository{store: store})
+
+	if _, err := first.Add("From first"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	// Listing sees the todo the first service added, so the second one
+	// changes it, instead of saving over it without a conflict.
+	todos, err := second.List(ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(todos) != 1 {
+		t.Fatalf("Expected 1 todo, got %d", len(todos))
+	}
+	if err := second.Complete(todos[0].ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(store.todos) != 1 || !store.todos[0].Completed {
+		t.Errorf("Expected todo 1 completed, got %+v", store.todos)
+	}
+}
@@ -0,0 +1,173 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Block records that the todo with the given ID cannot be completed before
+// the blocker is. Dependencies that would form a cycle are rejected.
+func (s *Service) Block(id, blockerID int) error {
+	return s.retry(func() error {
+		return s.block(id, blockerID)
+	})
+}
+
+// block is Block without retrying on conflicts.
+func (s *Service) block(id, blockerID int) error {
+	if id == blockerID {
+		return fmt.Errorf("todo with ID %d cannot block itself", id)
+	}
+
+	todo, err := s.get(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.get(blockerID); err != nil {
+		return err
+	}
+
+	for _, existing := range todo.BlockedBy {
+		if existing == blockerID {
+			return fmt.Errorf("todo with ID %d is already blocked by todo with ID %d", id, blockerID)
+		}
+	}
+
+	path, err := s.dependencyPath(blockerID, id)
+	if err != nil {
+		return err
+	}
+	if path != nil {
+		return fmt.Errorf("blocking todo with ID %d by todo with ID %d would create a cycle: %s", id, blockerID, JoinIDs(append([]int{id}, path...), " -> "))
+	}
+
+	s.markChanged(*todo)
+	todo.BlockedBy = append(todo.BlockedBy, blockerID)
+
+	return s.commit(ActionBlock, id)
+}
+
+// Unblock removes the dependency of a todo on a blocker.
+func (s *Service) Unblock(id, blockerID int) error {
+	return s.retry(func() error {
+		return s.unblock(id, blockerID)
+	})
+}
+
+// unblock is Unblock without retrying on conflicts.
+func (s *Service) unblock(id, blockerID int) error {
+	todo, err := s.get(id)
+	if err != nil {
+		return err
+	}
+
+	for i, existing := range todo.BlockedBy {
+		if existing == blockerID {
+			s.markChanged(*todo)
+			todo.BlockedBy = append(todo.BlockedBy[:i], todo.BlockedBy[i+1:]...)
+			if len(todo.BlockedBy) == 0 {
+				todo.BlockedBy = nil
+			}
+			return s.commit(ActionUnblock, id)
+		}
+	}
+
+	return fmt.Errorf("todo with ID %d is not blocked by todo with ID %d", id, blockerID)
+}
+
+// OpenBlockers returns the IDs of the blockers of a todo that are not completed.
+func (s *Service) OpenBlockers(todo Todo) ([]int, error) {
+	var open []int
+	for _, blockerID := range todo.BlockedBy {
+		blocker, err := s.lookup(blockerID)
+		if err != nil {
+			return nil, err
+		}
+		if blocker != nil && blocker.DeletedAt == nil && !blocker.Completed {
+			open = append(open, blockerID)
+		}
+	}
+	return open, nil
+}
+
+// FilterReady returns the pending todos that have no open blockers.
+func (s *Service) FilterReady(todos []Todo) ([]Todo, error) {
+	return s.filterBlockers(todos, false)
+}
+
+// FilterBlocked returns the pending todos that still have open blockers.
+func (s *Service) FilterBlocked(todos []Todo) ([]Todo, error) {
+	return s.filterBlockers(todos, true)
+}
+
+// filterBlockers returns the pending todos that have open blockers, or that
+// have none.
+func (s *Service) filterBlockers(todos []Todo, blocked bool) ([]Todo, error) {
+	var filtered []Todo
+	for _, todo := range todos {
+		if todo.Completed {
+			continue
+		}
+		blockers, err := s.OpenBlockers(todo)
+		if err != nil {
+			return nil, err
+		}
+		if (len(blockers) > 0) == blocked {
+			filtered = append(filtered, todo)
+		}
+	}
+	return filtered, nil
+}
+
+// dependencyPath returns the chain of IDs from one todo to another following
+// "blocked by" relations, or nil if to cannot be reached from.
+func (s *Service) dependencyPath(from, to int) ([]int, error) {
+	visited := make(map[int]bool)
+	var walk func(id int) ([]int, error)
+	walk = func(id int) ([]int, error) {
+		if id == to {
+			return []int{id}, nil
+		}
+		if visited[id] {
+			return nil, nil
+		}
+		visited[id] = true
+
+		todo, err := s.lookup(id)
+		if err != nil || todo == nil || todo.DeletedAt != nil {
+			return nil, err
+		}
+		for _, blockerID := range todo.BlockedBy {
+			path, err := walk(blockerID)
+			if err != nil {
+				return nil, err
+			}
+			if path != nil {
+				return append([]int{id}, path...), nil
+			}
+		}
+		return nil, nil
+	}
+	return walk(from)
+}
+
+// withoutIDs returns ids without the ones in removed, or nil if none remain.
+func withoutIDs(ids []int, removed map[int]bool) []int {
+	var kept []int
+	for _, id := range ids {
+		if !removed[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// JoinIDs formats todo IDs as "#1<sep>#2<sep>...", e.g. "#1, #2" with ", ".
+func JoinIDs(ids []int, sep string) string {
+	formatted := make([]string, len(ids))
+	for i, id := range ids {
+		formatted[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(formatted, sep)
+}
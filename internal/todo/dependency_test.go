@@ -0,0 +1,144 @@
+package todo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestService_Block(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	first, _ := service.Add("First")
+	second, _ := service.Add("Second")
+	third, _ := service.Add("Third")
+
+	if err := service.Block(first.ID, second.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := service.Block(second.ID, third.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	todoItem, _ := service.Get(first.ID)
+	if len(todoItem.BlockedBy) != 1 || todoItem.BlockedBy[0] != second.ID {
+		t.Errorf("Expected todo to be blocked by %d, got %v", second.ID, todoItem.BlockedBy)
+	}
+
+	// Test a duplicate dependency.
+	if err := service.Block(first.ID, second.ID); err == nil {
+		t.Error("Expected error for duplicate dependency")
+	}
+
+	// Test a todo blocking itself.
+	if err := service.Block(first.ID, first.ID); err == nil {
+		t.Error("Expected error when a todo blocks itself")
+	}
+
+	// Test a dependency cycle.
+	err := service.Block(third.ID, first.ID)
+	if err == nil {
+		t.Fatal("Expected error for dependency cycle")
+	}
+	if !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+
+	// Test a non-existent blocker.
+	if err := service.Block(first.ID, 999); err == nil {
+		t.Error("Expected error for non-existent blocker")
+	}
+}
+
+func TestService_Unblock(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	first, _ := service.Add("First")
+	second, _ := service.Add("Second")
+
+	if err := service.Block(first.ID, second.ID); err != nil {
+		t.Fatalf("Failed to block todo: %v", err)
+	}
+
+	if err := service.Unblock(first.ID, second.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	todoItem, _ := service.Get(first.ID)
+	if len(todoItem.BlockedBy) != 0 {
+		t.Errorf("Expected no blockers, got %v", todoItem.BlockedBy)
+	}
+
+	if err := service.Unblock(first.ID, second.ID); err == nil {
+		t.Error("Expected error when removing a missing dependency")
+	}
+}
+
+func TestService_Complete_WithBlockers(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	blocked, _ := service.Add("Release")
+	blocker, _ := service.Add("Fix tests")
+
+	if err := service.Block(blocked.ID, blocker.ID); err != nil {
+		t.Fatalf("Failed to block todo: %v", err)
+	}
+
+	if err := service.Complete(blocked.ID); err == nil {
+		t.Error("Expected error when completing a blocked todo")
+	}
+
+	if err := service.Complete(blocker.ID); err != nil {
+		t.Fatalf("Failed to complete blocker: %v", err)
+	}
+
+	if err := service.Complete(blocked.ID); err != nil {
+		t.Errorf("Unexpected error once blocker is completed: %v", err)
+	}
+}
+
+func TestService_ReadyAndBlocked(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	blocked, _ := service.Add("Release")
+	blocker, _ := service.Add("Fix tests")
+	done, _ := service.Add("Done")
+
+	if err := service.Block(blocked.ID, blocker.ID); err != nil {
+		t.Fatalf("Failed to block todo: %v", err)
+	}
+
+	if err := service.Complete(done.ID); err != nil {
+		t.Fatalf("Failed to complete todo: %v", err)
+	}
+
+	ready, err := service.FilterReady(listTodos(t, service))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ready) != 1 || ready[0].ID != blocker.ID {
+		t.Errorf("Expected only todo %d to be ready, got %v", blocker.ID, ready)
+	}
+
+	blockedTodos, err := service.FilterBlocked(listTodos(t, service))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(blockedTodos) != 1 || blockedTodos[0].ID != blocked.ID {
+		t.Errorf("Expected only todo %d to be blocked, got %v", blocked.ID, blockedTodos)
+	}
+
+	// Deleting the blocker releases the dependency.
+	if err := service.Delete(blocker.ID); err != nil {
+		t.Fatalf("Failed to delete todo: %v", err)
+	}
+
+	todoItem, _ := service.Get(blocked.ID)
+	if len(todoItem.BlockedBy) != 0 {
+		t.Errorf("Expected dependency on deleted todo to be removed, got %v", todoItem.BlockedBy)
+	}
+}
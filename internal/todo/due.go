@@ -0,0 +1,121 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateFormat is the layout used to read and display due dates.
+const DateFormat = "2006-01-02"
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// ParseDate interprets an ISO date (2006-01-02) or a natural-language phrase
+// relative to now. Supported phrases are "today", "tomorrow", "yesterday",
+// weekday names optionally prefixed with "next" (the next occurrence after
+// today), "next week", "next month", "in N day(s)|week(s)|month(s)" and
+// offsets such as "+7d", "-2w" or "+1m".
+// The result is always midnight in now's location.
+func ParseDate(input string, now time.Time) (time.Time, error) {
+	phrase := strings.ToLower(strings.Join(strings.Fields(input), " "))
+	today := StartOfDay(now)
+
+	if phrase == "" {
+		return time.Time{}, fmt.Errorf("date cannot be empty")
+	}
+
+	if date, err := time.ParseInLocation(DateFormat, phrase, now.Location()); err == nil {
+		return date, nil
+	}
+
+	switch phrase {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "next week":
+		return today.AddDate(0, 0, 7), nil
+	case "next month":
+		return today.AddDate(0, 1, 0), nil
+	}
+
+	if weekday, ok := weekdays[strings.TrimPrefix(phrase, "next ")]; ok {
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+
+	if offset, ok := parseDateOffset(phrase, today); ok {
+		return offset, nil
+	}
+
+	if fields := strings.Fields(phrase); len(fields) == 3 && fields[0] == "in" {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("invalid date %q: %q is not a valid count", input, fields[1])
+		}
+		switch strings.TrimSuffix(fields[2], "s") {
+		case "day":
+			return today.AddDate(0, 0, n), nil
+		case "week":
+			return today.AddDate(0, 0, 7*n), nil
+		case "month":
+			return today.AddDate(0, n, 0), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD, +7d or a phrase like \"tomorrow\", \"next friday\", \"in 3 days\")", input)
+}
+
+// parseDateOffset interprets "+Nd", "-Nw" or "+Nm" relative to today.
+func parseDateOffset(phrase string, today time.Time) (time.Time, bool) {
+	if len(phrase) < 3 || (phrase[0] != '+' && phrase[0] != '-') {
+		return time.Time{}, false
+	}
+
+	n, err := strconv.Atoi(phrase[1 : len(phrase)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	if phrase[0] == '-' {
+		n = -n
+	}
+
+	switch phrase[len(phrase)-1] {
+	case 'd':
+		return today.AddDate(0, 0, n), true
+	case 'w':
+		return today.AddDate(0, 0, 7*n), true
+	case 'm':
+		return today.AddDate(0, n, 0), true
+	}
+	return time.Time{}, false
+}
+
+// StartOfDay returns midnight of the day containing t.
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// IsOverdue reports whether the todo is pending and its due day has passed.
+func (t Todo) IsOverdue(now time.Time) bool {
+	if t.Completed || t.DueAt == nil {
+		return false
+	}
+	return t.DueAt.Before(StartOfDay(now))
+}
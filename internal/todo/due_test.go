@@ -0,0 +1,87 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	// Thursday.
+	now := time.Date(2024, 3, 14, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{name: "iso date", input: "2024-04-01", expected: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "today", input: "today", expected: time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{name: "tomorrow", input: "Tomorrow", expected: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "yesterday", input: "yesterday", expected: time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{name: "weekday", input: "monday", expected: time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{name: "next weekday", input: "next friday", expected: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "same weekday", input: "thursday", expected: time.Date(2024, 3, 21, 0, 0, 0, 0, time.UTC)},
+		{name: "next week", input: "next week", expected: time.Date(2024, 3, 21, 0, 0, 0, 0, time.UTC)},
+		{name: "next month", input: "next month", expected: time.Date(2024, 4, 14, 0, 0, 0, 0, time.UTC)},
+		{name: "in days", input: "in 3 days", expected: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{name: "in one day", input: "in 1 day", expected: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "in weeks", input: "in  2 weeks", expected: time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC)},
+		{name: "in months", input: "in 1 month", expected: time.Date(2024, 4, 14, 0, 0, 0, 0, time.UTC)},
+		{name: "offset days", input: "+7d", expected: time.Date(2024, 3, 21, 0, 0, 0, 0, time.UTC)},
+		{name: "negative offset weeks", input: "-2w", expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "offset months", input: "+1m", expected: time.Date(2024, 4, 14, 0, 0, 0, 0, time.UTC)},
+		{name: "invalid offset unit", input: "+7y", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "invalid count", input: "in many days", wantErr: true},
+		{name: "invalid unit", input: "in 3 fortnights", wantErr: true},
+		{name: "invalid phrase", input: "someday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseDate(tt.input, now)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if !result.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTodo_IsOverdue(t *testing.T) {
+	now := time.Date(2024, 3, 14, 15, 30, 0, 0, time.UTC)
+	yesterday := time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		todo     Todo
+		expected bool
+	}{
+		{name: "no due date", todo: Todo{}, expected: false},
+		{name: "due yesterday", todo: Todo{DueAt: &yesterday}, expected: true},
+		{name: "due today", todo: Todo{DueAt: &today}, expected: false},
+		{name: "completed", todo: Todo{DueAt: &yesterday, Completed: true}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.todo.IsOverdue(now); result != tt.expected {
+				t.Errorf("Expected overdue %t, got %t", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package todo
+
+import (
+	"fmt"
+	"time"
+)
+
+// TreeNode is a todo positioned in a flattened parent-child tree.
+type TreeNode struct {
+	Todo  Todo
+	Depth int
+}
+
+// CompleteOption configures how Complete treats subtasks.
+type CompleteOption func(*completeConfig)
+
+type completeConfig struct {
+	cascade bool
+	force   bool
+}
+
+// CompleteCascade also completes every open subtask of the todo.
+func CompleteCascade() CompleteOption {
+	return func(c *completeConfig) {
+		c.cascade = true
+	}
+}
+
+// CompleteForce completes the todo even if it has open subtasks.
+func CompleteForce() CompleteOption {
+	return func(c *completeConfig) {
+		c.force = true
+	}
+}
+
+// DeleteOption configures how Delete treats subtasks.
+type DeleteOption func(*deleteConfig)
+
+type deleteConfig struct {
+	cascade bool
+}
+
+// DeleteCascade also deletes every subtask of the todo. Without it, subtasks
+// are re-parented to the deleted todo's parent.
+func DeleteCascade() DeleteOption {
+	return func(c *deleteConfig) {
+		c.cascade = true
+	}
+}
+
+// WithParent makes a new todo a subtask of the todo with the given ID.
+func WithParent(parentID int) AddOption {
+	return func(t *Todo) {
+		t.ParentID = parentID
+	}
+}
+
+// GetChildren returns the direct subtasks of a todo.
+func (s *Service) GetChildren(id int) ([]Todo, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	children, err := s.list(ListOptions{Trash: WithoutTrash, ParentID: id})
+	if err != nil {
+		return nil, err
+	}
+	return todoValues(children), nil
+}
+
+// GetDescendants returns all subtasks of a todo, depth first.
+func (s *Service) GetDescendants(id int) ([]Todo, error) {
+	children, err := s.GetChildren(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var descendants []Todo
+	for _, child := range children {
+		grandchildren, err := s.GetDescendants(child.ID)
+		if err != nil {
+			return nil, err
+		}
+		descendants = append(descendants, child)
+		descendants = append(descendants, grandchildren...)
+	}
+	return descendants, nil
+}
+
+// BuildTree orders todos depth first so each subtask follows its parent.
+// Todos whose parent is not part of the given slice are treated as roots.
+func BuildTree(todos []Todo) []TreeNode {
+	present := make(map[int]bool, len(todos))
+	for _, todo := range todos {
+		present[todo.ID] = true
+	}
+
+	children := make(map[int][]Todo)
+	var roots []Todo
+	for _, todo := range todos {
+		if todo.ParentID != 0 && present[todo.ParentID] {
+			children[todo.ParentID] = append(children[todo.ParentID], todo)
+		} else {
+			roots = append(roots, todo)
+		}
+	}
+
+	nodes := make([]TreeNode, 0, len(todos))
+	var walk func(todo Todo, depth int)
+	walk = func(todo Todo, depth int) {
+		nodes = append(nodes, TreeNode{Todo: todo, Depth: depth})
+		for _, child := range children[todo.ID] {
+			walk(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+
+	return nodes
+}
+
+// validateParent checks that parentID can become the parent of the todo with
+// the given ID without creating a cycle. An id of 0 denotes a new todo.
+func (s *Service) validateParent(id, parentID int) error {
+	if parentID == 0 {
+		return nil
+	}
+
+	if parentID == id {
+		return fmt.Errorf("todo with ID %d cannot be its own parent", id)
+	}
+
+	// Walk up from the new parent; meeting the todo itself means a cycle.
+	visited := make(map[int]bool)
+	for current := parentID; current != 0 && !visited[current]; {
+		visited[current] = true
+		parent, err := s.lookup(current)
+		if err != nil {
+			return err
+		}
+		if parent == nil || parent.DeletedAt != nil {
+			return fmt.Errorf("parent todo with ID %d not found", current)
+		}
+		if id != 0 && parent.ParentID == id {
+			return fmt.Errorf("todo with ID %d is a subtask of todo with ID %d", parentID, id)
+		}
+		current = parent.ParentID
+	}
+
+	return nil
+}
+
+// completeDescendants marks all open subtasks of a todo as completed.
+func (s *Service) completeDescendants(id int, now time.Time) error {
+	descendants, err := s.GetDescendants(id)
+	if err != nil {
+		return err
+	}
+	for _, descendant := range descendants {
+		todo, err := s.get(descendant.ID)
+		if err != nil {
+			return err
+		}
+		if todo.Completed {
+			continue
+		}
+		s.markChanged(*todo)
+		todo.Completed = true
+		completedAt := now
+		todo.CompletedAt = &completedAt
+	}
+	return nil
+}
+
+// openDescendants returns the IDs of the subtasks of a todo that are still open.
+func (s *Service) openDescendants(id int) ([]int, error) {
+	descendants, err := s.GetDescendants(id)
+	if err != nil {
+		return nil, err
+	}
+	var open []int
+	for _, descendant := range descendants {
+		if !descendant.Completed {
+			open = append(open, descendant.ID)
+		}
+	}
+	return open, nil
+}
+
+// todoValues copies the todos pointed to.
+func todoValues(todos []*Todo) []Todo {
+	values := make([]Todo, len(todos))
+	for i, todo := range todos {
+		values[i] = *todo
+	}
+	return values
+}
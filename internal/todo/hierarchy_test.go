@@ -0,0 +1,182 @@
+package todo
+
+import (
+	"testing"
+)
+
+func TestService_Add_WithParent(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	parent, err := service.Add("Epic")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	child, err := service.Add("Write tests", WithParent(parent.ID))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if child.ParentID != parent.ID {
+		t.Errorf("Expected parent ID %d, got %d", parent.ID, child.ParentID)
+	}
+
+	children, err := service.GetChildren(parent.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(children) != 1 || children[0].ID != child.ID {
+		t.Errorf("Expected child %d, got %v", child.ID, children)
+	}
+
+	// Test adding a subtask to a non-existent parent.
+	if _, err := service.Add("Orphan", WithParent(999)); err == nil {
+		t.Error("Expected error for non-existent parent")
+	}
+}
+
+func TestService_Update_ParentCycle(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	root, _ := service.Add("Root")
+	child, _ := service.Add("Child", WithParent(root.ID))
+	grandchild, _ := service.Add("Grandchild", WithParent(child.ID))
+
+	rootID := root.ID
+	if _, err := service.Update(rootID, Update{ParentID: &grandchild.ID}); err == nil {
+		t.Error("Expected error when creating a cycle")
+	}
+
+	if _, err := service.Update(rootID, Update{ParentID: &rootID}); err == nil {
+		t.Error("Expected error when making a todo its own parent")
+	}
+
+	topLevel := 0
+	updated, err := service.Update(grandchild.ID, Update{ParentID: &topLevel})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if updated.ParentID != 0 {
+		t.Errorf("Expected todo to become top-level, got parent %d", updated.ParentID)
+	}
+}
+
+func TestService_Complete_WithSubtasks(t *testing.T) {
+	tests := []struct {
+		name               string
+		opts               []CompleteOption
+		wantErr            bool
+		expectChildrenDone bool
+	}{
+		{name: "refuses with open subtasks", wantErr: true},
+		{name: "cascade", opts: []CompleteOption{CompleteCascade()}, expectChildrenDone: true},
+		{name: "force", opts: []CompleteOption{CompleteForce()}, expectChildrenDone: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockRepository{}
+			service := NewService(repo)
+
+			parent, _ := service.Add("Epic")
+			child, _ := service.Add("Child", WithParent(parent.ID))
+			grandchild, _ := service.Add("Grandchild", WithParent(child.ID))
+
+			err := service.Complete(parent.ID, tt.opts...)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for _, id := range []int{child.ID, grandchild.ID} {
+				todoItem, _ := service.Get(id)
+				if todoItem.Completed != tt.expectChildrenDone {
+					t.Errorf("Todo %d: expected completed %t, got %t", id, tt.expectChildrenDone, todoItem.Completed)
+				}
+			}
+		})
+	}
+}
+
+func TestService_Delete_WithSubtasks(t *testing.T) {
+	t.Run("re-parents subtasks", func(t *testing.T) {
+		service := NewService(&MockRepository{})
+
+		root, _ := service.Add("Root")
+		middle, _ := service.Add("Middle", WithParent(root.ID))
+		leaf, _ := service.Add("Leaf", WithParent(middle.ID))
+
+		if err := service.Delete(middle.ID); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		todoItem, err := service.Get(leaf.ID)
+		if err != nil {
+			t.Fatalf("Expected subtask to survive: %v", err)
+		}
+
+		if todoItem.ParentID != root.ID {
+			t.Errorf("Expected subtask to move to parent %d, got %d", root.ID, todoItem.ParentID)
+		}
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		service := NewService(&MockRepository{})
+
+		root, _ := service.Add("Root")
+		middle, _ := service.Add("Middle", WithParent(root.ID))
+		_, _ = service.Add("Leaf", WithParent(middle.ID))
+		_, _ = service.Add("Unrelated")
+
+		if err := service.Delete(root.ID, DeleteCascade()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(listTodos(t, service)) != 1 {
+			t.Errorf("Expected 1 remaining todo, got %d", len(listTodos(t, service)))
+		}
+	})
+}
+
+func TestBuildTree(t *testing.T) {
+	todos := []Todo{
+		{ID: 1},
+		{ID: 2, ParentID: 1},
+		{ID: 3},
+		{ID: 4, ParentID: 2},
+		{ID: 5, ParentID: 1},
+		{ID: 6, ParentID: 99},
+	}
+
+	nodes := BuildTree(todos)
+
+	expected := []TreeNode{
+		{Todo: todos[0], Depth: 0},
+		{Todo: todos[1], Depth: 1},
+		{Todo: todos[3], Depth: 2},
+		{Todo: todos[4], Depth: 1},
+		{Todo: todos[2], Depth: 0},
+		{Todo: todos[5], Depth: 0},
+	}
+
+	if len(nodes) != len(expected) {
+		t.Fatalf("Expected %d nodes, got %d", len(expected), len(nodes))
+	}
+
+	for i, node := range nodes {
+		if node.Todo.ID != expected[i].Todo.ID || node.Depth != expected[i].Depth {
+			t.Errorf("Node %d: expected ID %d at depth %d, got ID %d at depth %d",
+				i, expected[i].Todo.ID, expected[i].Depth, node.Todo.ID, node.Depth)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields reported in a Change besides the todo's own fields.
+const (
+	// FieldTodo marks a todo being created (New holds its description) or
+	// permanently removed from the active todos (Old holds it).
+	FieldTodo = "todo"
+)
+
+// Change is one field of a todo changed by a mutation, as recorded in the
+// history.
+type Change struct {
+	TodoID int       `json:"todo_id"`
+	Action string    `json:"action"`
+	Field  string    `json:"field"`
+	Old    string    `json:"old,omitempty"`
+	New    string    `json:"new,omitempty"`
+	By     string    `json:"by,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// HistoryRepository is implemented by repositories that can persist the
+// history. Changes are only ever appended, so the history is read only when
+// it is asked for.
+type HistoryRepository interface {
+	AppendHistory(changes []Change) error
+	LoadHistory() ([]Change, error)
+}
+
+// SetActor sets who is making changes, as recorded in the history.
+func (s *Service) SetActor(actor string) {
+	s.actor = actor
+}
+
+// History returns the changes made to a todo, oldest first.
+func (s *Service) History(id int) ([]Change, error) {
+	changes, err := s.loadHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	var history []Change
+	for _, change := range changes {
+		if change.TodoID == id {
+			history = append(history, change)
+		}
+	}
+	if len(history) == 0 {
+		if _, err := s.Get(id); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+// Log returns the changes made to all todos at or after the given time,
+// oldest first.
+func (s *Service) Log(since time.Time) ([]Change, error) {
+	changes, err := s.loadHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	var log []Change
+	for _, change := range changes {
+		if !change.At.Before(since) {
+			log = append(log, change)
+		}
+	}
+	return log, nil
+}
+
+func (s *Service) loadHistory() ([]Change, error) {
+	repo, ok := s.repo.(HistoryRepository)
+	if !ok {
+		return s.history, nil
+	}
+
+	changes, err := repo.LoadHistory()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].At.Before(changes[j].At)
+	})
+	return changes, nil
+}
+
+// historyChanges returns the field changes made to the todos changed since
+// the last save, given as they were and as they are.
+func (s *Service) historyChanges(action string, before, after []Todo) []Change {
+	if len(before) == 0 && len(after) == 0 {
+		return nil
+	}
+
+	old := make(map[int]Todo, len(before))
+	for _, todo := range before {
+		old[todo.ID] = todo
+	}
+
+	now := time.Now()
+	var changes []Change
+	add := func(id int, field, oldValue, newValue string) {
+		changes = append(changes, Change{
+			TodoID: id,
+			Action: action,
+			Field:  field,
+			Old:    oldValue,
+			New:    newValue,
+			By:     s.actor,
+			At:     now,
+		})
+	}
+
+	for _, todo := range after {
+		was, existed := old[todo.ID]
+		delete(old, todo.ID)
+		if !existed {
+			add(todo.ID, FieldTodo, "", todo.Description)
+			continue
+		}
+
+		wasValues, values := historyValues(was), historyValues(todo)
+		for i, value := range values {
+			if value[1] != wasValues[i][1] {
+				add(todo.ID, value[0], wasValues[i][1], value[1])
+			}
+		}
+		for _, note := range todo.Notes[min(len(was.Notes), len(todo.Notes)):] {
+			add(todo.ID, FieldNote, "", note.Text)
+		}
+	}
+
+	for _, todo := range before {
+		if _, removed := old[todo.ID]; removed {
+			add(todo.ID, FieldTodo, todo.Description, "")
+		}
+	}
+
+	return changes
+}
+
+// appendHistory adds changes to the history.
+func (s *Service) appendHistory(changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	if repo, ok := s.repo.(HistoryRepository); ok {
+		return repo.AppendHistory(changes)
+	}
+	s.history = append(s.history, changes...)
+	return nil
+}
+
+// historyValues returns the tracked fields of a todo and their values as
+// text, in a fixed order.
+func historyValues(todo Todo) [][2]string {
+	var due, recurrence, parent, deleted string
+	if todo.DueAt != nil {
+		due = todo.DueAt.Format(DateFormat)
+	}
+	if todo.Recurrence != nil {
+		recurrence = todo.Recurrence.String()
+	}
+	if todo.ParentID != 0 {
+		parent = "#" + strconv.Itoa(todo.ParentID)
+	}
+	if todo.DeletedAt != nil {
+		deleted = todo.DeletedAt.Format(time.RFC3339)
+	}
+
+	return [][2]string{
+		{FieldDescription, todo.Description},
+		{"completed", strconv.FormatBool(todo.Completed)},
+		{"priority", todo.Priority.String()},
+		{"due", due},
+		{"tags", strings.Join(todo.Tags, ",")},
+		{"project", todo.Project},
+		{"parent", parent},
+		{"blocked_by", JoinIDs(todo.BlockedBy, ", ")},
+		{"recurrence", recurrence},
+		{"deleted", deleted},
+	}
+}
+
+// String describes the change, e.g. `priority: "low" -> "high"`.
+func (c Change) String() string {
+	switch {
+	case c.Field == FieldTodo && c.Old == "":
+		return fmt.Sprintf("created %q", c.New)
+	case c.Field == FieldTodo:
+		return fmt.Sprintf("removed %q", c.Old)
+	case c.Field == FieldNote:
+		return fmt.Sprintf("note: %q", c.New)
+	}
+	return fmt.Sprintf("%s: %q -> %q", c.Field, c.Old, c.New)
+}
@@ -0,0 +1,184 @@
+package todo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// MockHistoryRepository is a MockRepository that also persists the history.
+type MockHistoryRepository struct {
+	MockRepository
+	history []Change
+}
+
+func (m *MockHistoryRepository) AppendHistory(changes []Change) error {
+	m.history = append(m.history, changes...)
+	return nil
+}
+
+func (m *MockHistoryRepository) LoadHistory() ([]Change, error) {
+	result := make([]Change, len(m.history))
+	copy(result, m.history)
+	return result, nil
+}
+
+// MockFailingHistoryRepository is a MockRepository that fails to append to
+// the history.
+type MockFailingHistoryRepository struct {
+	MockRepository
+}
+
+func (m *MockFailingHistoryRepository) AppendHistory(_ []Change) error {
+	return errors.New("disk full")
+}
+
+func (m *MockFailingHistoryRepository) LoadHistory() ([]Change, error) {
+	return nil, nil
+}
+
+func TestService_History_AppendFails(t *testing.T) {
+	repo := &MockFailingHistoryRepository{}
+	service := NewService(repo)
+	var warnings bytes.Buffer
+	service.warnings = &warnings
+
+	if _, err := service.Add("Saved anyway"); err != nil {
+		t.Fatalf("Expected the todo to be saved, got %v", err)
+	}
+	if len(repo.todos) != 1 {
+		t.Errorf("Expected 1 stored todo, got %d", len(repo.todos))
+	}
+	if !strings.Contains(warnings.String(), "disk full") {
+		t.Errorf("Expected a warning about the history, got %q", warnings.String())
+	}
+
+	// Later mutations are saved as well.
+	if err := service.Complete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !repo.todos[0].Completed {
+		t.Error("Expected the todo to be completed")
+	}
+}
+
+func TestService_History(t *testing.T) {
+	repo := &MockHistoryRepository{}
+	service := NewService(repo)
+	service.SetActor("alice")
+
+	if _, err := service.Add("Write report", WithPriority(PriorityLow)); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if err := service.SetPriority(1, PriorityHigh); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.AddNote(1, "Draft done"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	service.SetActor("bob")
+	if err := service.Complete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.Incomplete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	history, err := NewService(repo).History(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Change{
+		{Action: ActionAdd, Field: FieldTodo, New: "Write report", By: "alice"},
+		{Action: ActionEdit, Field: "priority", Old: "low", New: "high", By: "alice"},
+		{Action: ActionNote, Field: FieldNote, New: "Draft done", By: "alice"},
+		{Action: ActionComplete, Field: "completed", Old: "false", New: "true", By: "bob"},
+		{Action: ActionIncomplete, Field: "completed", Old: "true", New: "false", By: "bob"},
+	}
+
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d: %v", len(expected), len(history), history)
+	}
+	for i, want := range expected {
+		got := history[i]
+		if got.TodoID != 1 || got.Action != want.Action || got.Field != want.Field || got.Old != want.Old || got.New != want.New || got.By != want.By {
+			t.Errorf("Change %d: expected %+v, got %+v", i, want, got)
+		}
+		if got.At.IsZero() {
+			t.Errorf("Change %d: At should be set", i)
+		}
+	}
+}
+
+func TestService_History_Delete(t *testing.T) {
+	service := NewService(&MockRepository{})
+	if _, err := service.Add("Parent"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if _, err := service.Add("Child", WithParent(1)); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if err := service.Delete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Deleting re-parents the subtask, which is recorded too.
+	history, err := service.History(2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	last := history[len(history)-1]
+	if last.Action != ActionDelete || last.Field != "parent" || last.Old != "#1" || last.New != "" {
+		t.Errorf("Expected parent change by delete, got %+v", last)
+	}
+
+	// The history of a deleted todo is still available.
+	history, err = service.History(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if last := history[len(history)-1]; last.Field != "deleted" || last.New == "" {
+		t.Errorf("Expected deleted change, got %+v", last)
+	}
+
+	if _, err := service.History(999); err == nil {
+		t.Error("Expected error for non-existent todo, got nil")
+	}
+}
+
+func TestService_Log(t *testing.T) {
+	service := NewService(&MockRepository{})
+	if _, err := service.Add("First"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if _, err := service.Add("Second"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	service.history[0].At = time.Now().AddDate(0, 0, -10)
+
+	tests := []struct {
+		name     string
+		since    time.Time
+		expected int
+	}{
+		{"everything", time.Time{}, 2},
+		{"last week", time.Now().AddDate(0, 0, -7), 1},
+		{"future", time.Now().Add(time.Hour), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := service.Log(tt.since)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(log) != tt.expected {
+				t.Errorf("Expected %d changes, got %d", tt.expected, len(log))
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package todo
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// maxJournalEntries bounds the number of changes that can be undone.
+const maxJournalEntries = 100
+
+// Actions recorded in the journal and the history. Only the history records
+// undo, redo, purge, archive and rename project.
+const (
+	ActionAdd            = "add"
+	ActionComplete       = "complete"
+	ActionIncomplete     = "incomplete"
+	ActionDelete         = "delete"
+	ActionEdit           = "edit"
+	ActionBlock          = "block"
+	ActionUnblock        = "unblock"
+	ActionNote           = "note"
+	ActionStopRecurrence = "stop recurrence"
+	ActionRestore        = "restore"
+	ActionUndo           = "undo"
+	ActionRedo           = "redo"
+	ActionPurge          = "purge"
+	ActionArchive        = "archive"
+	ActionRenameProject  = "rename project"
+)
+
+// JournalEntry records one mutation as the todos it changed. Before holds
+// the changed todos as they were and After as they became; a todo missing
+// from Before was created and one missing from After was deleted.
+type JournalEntry struct {
+	Action string    `json:"action"`
+	ID     int       `json:"id"`
+	IDs    []int     `json:"ids,omitempty"`
+	At     time.Time `json:"at"`
+	Before []Todo    `json:"before,omitempty"`
+	After  []Todo    `json:"after,omitempty"`
+}
+
+// String describes the entry, e.g. "complete #3" or, for a change made to
+// several todos at once, "complete #3, #5".
+func (e JournalEntry) String() string {
+	if len(e.IDs) > 1 {
+		return fmt.Sprintf("%s %s", e.Action, JoinIDs(e.IDs, ", "))
+	}
+	return fmt.Sprintf("%s #%d", e.Action, e.ID)
+}
+
+// Journal holds the changes that can be undone and the undone changes that
+// can be redone, most recent last.
+type Journal struct {
+	Undo []JournalEntry `json:"undo"`
+	Redo []JournalEntry `json:"redo"`
+}
+
+// JournalRepository is implemented by repositories that can persist the
+// undo journal. With other repositories changes can only be undone until
+// the service is discarded.
+type JournalRepository interface {
+	SaveJournal(journal Journal) error
+	LoadJournal() (Journal, error)
+}
+
+// Undo reverts the last n changes, most recent first, and returns them.
+func (s *Service) Undo(n int) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	err := s.retry(func() error {
+		var err error
+		entries, err = s.undo(n)
+		return err
+	})
+	return entries, err
+}
+
+// undo is Undo without retrying on conflicts.
+func (s *Service) undo(n int) ([]JournalEntry, error) {
+	entries, err := popJournal(&s.journal.Undo, n, "undo")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if err := s.applyJournal(entry.Before, entry.After); err != nil {
+			return nil, err
+		}
+		s.journal.Redo = append(s.journal.Redo, entry)
+	}
+
+	return entries, s.save(ActionUndo)
+}
+
+// Redo reapplies the last n undone changes and returns them. Any new change
+// discards the changes that can be redone.
+func (s *Service) Redo(n int) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	err := s.retry(func() error {
+		var err error
+		entries, err = s.redo(n)
+		return err
+	})
+	return entries, err
+}
+
+// redo is Redo without retrying on conflicts.
+func (s *Service) redo(n int) ([]JournalEntry, error) {
+	entries, err := popJournal(&s.journal.Redo, n, "redo")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if err := s.applyJournal(entry.After, entry.Before); err != nil {
+			return nil, err
+		}
+		s.journal.Undo = append(s.journal.Undo, entry)
+	}
+
+	return entries, s.save(ActionRedo)
+}
+
+// popJournal removes the last n entries from a journal stack, most recent
+// first.
+func popJournal(stack *[]JournalEntry, n int, verb string) ([]JournalEntry, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("number of changes to %s must be at least 1", verb)
+	}
+	if len(*stack) == 0 {
+		return nil, fmt.Errorf("nothing to %s", verb)
+	}
+	if n > len(*stack) {
+		return nil, fmt.Errorf("only %d change(s) to %s", len(*stack), verb)
+	}
+
+	var entries []JournalEntry
+	for i := 0; i < n; i++ {
+		last := len(*stack) - 1
+		entries = append(entries, (*stack)[last])
+		*stack = (*stack)[:last]
+	}
+	return entries, nil
+}
+
+// applyJournal puts back the todos in restore and removes the todos in
+// replace that restore does not contain.
+func (s *Service) applyJournal(restore, replace []Todo) error {
+	for _, todos := range [][]Todo{replace, restore} {
+		for _, todo := range todos {
+			current, err := s.lookup(todo.ID)
+			if err != nil {
+				return err
+			}
+			if current != nil {
+				s.markChanged(*current)
+				s.todos[todo.ID] = nil
+			}
+		}
+	}
+	for _, todo := range restore {
+		s.markCreated(cloneTodo(todo))
+	}
+	return nil
+}
+
+// commit records the changes made by an action to the given todos since the
+// last save in the journal and saves.
+func (s *Service) commit(action string, ids ...int) error {
+	entry := JournalEntry{Action: action, ID: ids[0], At: time.Now()}
+	if len(ids) > 1 {
+		entry.IDs = ids
+	}
+	entry.Before, entry.After = s.changes()
+
+	if len(entry.Before) > 0 || len(entry.After) > 0 {
+		s.journal.Undo = append(s.journal.Undo, entry)
+		if len(s.journal.Undo) > maxJournalEntries {
+			s.journal.Undo = s.journal.Undo[len(s.journal.Undo)-maxJournalEntries:]
+		}
+		s.journal.Redo = nil
+	}
+
+	return s.save(action)
+}
+
+// markChanged records a todo as it is before a mutation changes it, unless
+// it changed before since the last save.
+func (s *Service) markChanged(todo Todo) {
+	if _, ok := s.changed[todo.ID]; ok {
+		return
+	}
+	if s.changed == nil {
+		s.changed = make(map[int]*Todo)
+	}
+	was := cloneTodo(todo)
+	s.changed[todo.ID] = &was
+}
+
+// markCreated records a todo a mutation creates, or puts back, and adds it
+// to the todos the mutation changes.
+func (s *Service) markCreated(todo Todo) {
+	s.todos[todo.ID] = &todo
+	if _, ok := s.changed[todo.ID]; ok {
+		return
+	}
+	if s.changed == nil {
+		s.changed = make(map[int]*Todo)
+	}
+	s.changed[todo.ID] = nil
+}
+
+// changes returns the todos changed since the last save, as they were and
+// as they are, ordered by ID. Todos changed back to how they were are left
+// out.
+func (s *Service) changes() (before, after []Todo) {
+	for _, id := range slices.Sorted(maps.Keys(s.changed)) {
+		was, todo := s.changed[id], s.todos[id]
+		if was != nil && todo != nil && reflect.DeepEqual(*was, *todo) {
+			continue
+		}
+		if was != nil {
+			before = append(before, cloneTodo(*was))
+		}
+		if todo != nil {
+			after = append(after, cloneTodo(*todo))
+		}
+	}
+	return before, after
+}
+
+// revertChanges puts back the todos changed since the last save as they
+// were then.
+func (s *Service) revertChanges() {
+	for id, was := range s.changed {
+		if was == nil {
+			s.todos[id] = nil
+			continue
+		}
+		reverted := cloneTodo(*was)
+		s.todos[id] = &reverted
+	}
+	s.changed = nil
+}
+
+// forgetJournal drops the entries that change any of the given todos, such
+// as todos moved to the archive, so they cannot be brought back twice.
+func (s *Service) forgetJournal(ids map[int]bool) {
+	keep := func(entries []JournalEntry) []JournalEntry {
+		var kept []JournalEntry
+		for _, entry := range entries {
+			touches := len(withoutTodos(entry.Before, ids)) != len(entry.Before) ||
+				len(withoutTodos(entry.After, ids)) != len(entry.After)
+			if !touches {
+				kept = append(kept, entry)
+			}
+		}
+		return kept
+	}
+	s.journal.Undo = keep(s.journal.Undo)
+	s.journal.Redo = keep(s.journal.Redo)
+}
+
+func (s *Service) saveJournal() error {
+	repo, ok := s.repo.(JournalRepository)
+	if !ok {
+		return nil
+	}
+	return repo.SaveJournal(s.journal)
+}
+
+func (s *Service) loadJournal() error {
+	repo, ok := s.repo.(JournalRepository)
+	if !ok {
+		return nil
+	}
+
+	journal, err := repo.LoadJournal()
+	if err != nil {
+		return err
+	}
+
+	s.journal = journal
+	return nil
+}
+
+// cloneTodos copies todos so that later changes to their slices, such as
+// Unblock removing a blocker in place, do not affect the copy.
+func cloneTodos(todos []Todo) []Todo {
+	cloned := make([]Todo, len(todos))
+	for i, todo := range todos {
+		cloned[i] = cloneTodo(todo)
+	}
+	return cloned
+}
+
+func cloneTodo(todo Todo) Todo {
+	todo.Tags = slices.Clone(todo.Tags)
+	todo.BlockedBy = slices.Clone(todo.BlockedBy)
+	todo.Notes = slices.Clone(todo.Notes)
+	return todo
+}
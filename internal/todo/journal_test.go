@@ -0,0 +1,174 @@
+package todo
+
+import (
+	"reflect"
+	"testing"
+)
+
+// MockJournalRepository is a MockRepository that also persists the journal.
+type MockJournalRepository struct {
+	MockRepository
+	journal Journal
+}
+
+func (m *MockJournalRepository) SaveJournal(journal Journal) error {
+	m.journal = journal
+	return nil
+}
+
+func (m *MockJournalRepository) LoadJournal() (Journal, error) {
+	return m.journal, nil
+}
+
+func TestService_Undo(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		mutate func(s *Service) error
+	}{
+		{"complete", ActionComplete, func(s *Service) error { return s.Complete(2) }},
+		{"complete cascade", ActionComplete, func(s *Service) error { return s.Complete(1, CompleteCascade()) }},
+		{"incomplete", ActionIncomplete, func(s *Service) error { return s.Incomplete(3) }},
+		{"delete", ActionDelete, func(s *Service) error { return s.Delete(1) }},
+		{"delete cascade", ActionDelete, func(s *Service) error { return s.Delete(1, DeleteCascade()) }},
+		{"edit", ActionEdit, func(s *Service) error {
+			description := "Changed"
+			_, err := s.Update(2, Update{Description: &description, ClearDueAt: true})
+			return err
+		}},
+		{"block", ActionBlock, func(s *Service) error { return s.Block(1, 3) }},
+		{"note", ActionNote, func(s *Service) error { _, err := s.AddNote(1, "A note"); return err }},
+		{"add", ActionAdd, func(s *Service) error { _, err := s.Add("New", WithTags("x")); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&MockRepository{})
+			if _, err := service.Add("Parent", WithTags("a")); err != nil {
+				t.Fatalf("Failed to add todo: %v", err)
+			}
+			if _, err := service.Add("Child", WithParent(1)); err != nil {
+				t.Fatalf("Failed to add todo: %v", err)
+			}
+			if _, err := service.Add("Done"); err != nil {
+				t.Fatalf("Failed to add todo: %v", err)
+			}
+			if err := service.Complete(3); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			original := cloneTodos(listTodos(t, service))
+
+			if err := tt.mutate(service); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			changed := cloneTodos(listTodos(t, service))
+
+			entries, err := service.Undo(1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(entries) != 1 || entries[0].Action != tt.action {
+				t.Errorf("Expected one %s entry, got %v", tt.action, entries)
+			}
+			assertTodos(t, "after undo", listTodos(t, service), original)
+
+			if _, err := service.Redo(1); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			assertTodos(t, "after redo", listTodos(t, service), changed)
+		})
+	}
+}
+
+func TestService_Undo_Several(t *testing.T) {
+	service := NewService(&MockRepository{})
+	for _, description := range []string{"First", "Second", "Third"} {
+		if _, err := service.Add(description); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+
+	entries, err := service.Undo(2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != 3 || entries[1].ID != 2 {
+		t.Errorf("Expected to undo #3 then #2, got %v", entries)
+	}
+	if len(listTodos(t, service)) != 1 {
+		t.Errorf("Expected 1 todo, got %d", len(listTodos(t, service)))
+	}
+
+	if _, err := service.Undo(2); err == nil {
+		t.Error("Expected error when undoing more changes than recorded, got nil")
+	}
+
+	// A new change discards what could be redone and does not reuse IDs.
+	added, err := service.Add("Fourth")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if added.ID != 4 {
+		t.Errorf("Expected ID 4, got %d", added.ID)
+	}
+	if _, err := service.Redo(1); err == nil {
+		t.Error("Expected error when nothing can be redone, got nil")
+	}
+}
+
+func TestService_Undo_Empty(t *testing.T) {
+	service := NewService(&MockRepository{})
+
+	if _, err := service.Undo(1); err == nil {
+		t.Error("Expected error when nothing can be undone, got nil")
+	}
+	if _, err := service.Undo(0); err == nil {
+		t.Error("Expected error for a count of 0, got nil")
+	}
+}
+
+func TestService_Undo_Persisted(t *testing.T) {
+	repo := &MockJournalRepository{}
+	service := NewService(repo)
+	if _, err := service.Add("Keep me"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if err := service.Delete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A new service picks up the journal of the previous one.
+	service = NewService(repo)
+	if _, err := service.Undo(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	restored, err := NewService(repo).Get(1)
+	if err != nil {
+		t.Fatalf("Expected deleted todo to be restored: %v", err)
+	}
+	if restored.Description != "Keep me" {
+		t.Errorf("Expected 'Keep me', got %q", restored.Description)
+	}
+	if len(repo.journal.Redo) != 1 {
+		t.Errorf("Expected 1 change to redo, got %d", len(repo.journal.Redo))
+	}
+}
+
+func assertTodos(t *testing.T, when string, got, expected []Todo) {
+	t.Helper()
+
+	if len(got) != len(expected) {
+		t.Fatalf("%s: expected %d todos, got %d", when, len(expected), len(got))
+	}
+	want := make(map[int]Todo, len(expected))
+	for _, todo := range expected {
+		want[todo.ID] = todo
+	}
+	for _, todo := range got {
+		if was, ok := want[todo.ID]; !ok || !reflect.DeepEqual(was, todo) {
+			t.Errorf("%s: expected %v, got %v", when, expected, got)
+			return
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Note is a timestamped comment attached to a todo.
+type Note struct {
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddNote appends a comment to a todo.
+func (s *Service) AddNote(id int, text string) (*Note, error) {
+	var note *Note
+	err := s.retry(func() error {
+		var err error
+		note, err = s.addNote(id, text)
+		return err
+	})
+	return note, err
+}
+
+// addNote is AddNote without retrying on conflicts.
+func (s *Service) addNote(id int, text string) (*Note, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("note cannot be empty")
+	}
+
+	todo, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	note := Note{
+		Text:      text,
+		CreatedAt: time.Now(),
+	}
+	s.markChanged(*todo)
+	todo.Notes = append(todo.Notes, note)
+
+	if err := s.commit(ActionNote, id); err != nil {
+		return nil, fmt.Errorf("failed to save note: %w", err)
+	}
+
+	return &note, nil
+}
@@ -0,0 +1,55 @@
+package todo
+
+import (
+	"testing"
+)
+
+func TestService_AddNote(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	addedTodo, err := service.Add("Test todo")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	note, err := service.AddNote(addedTodo.ID, "  Waiting on the vendor  ")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if note.Text != "Waiting on the vendor" {
+		t.Errorf("Expected trimmed note text, got %q", note.Text)
+	}
+
+	if note.CreatedAt.IsZero() {
+		t.Error("CreatedAt should be set")
+	}
+
+	if _, err := service.AddNote(addedTodo.ID, "Vendor replied"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	todoItem, _ := service.Get(addedTodo.ID)
+	if len(todoItem.Notes) != 2 {
+		t.Fatalf("Expected 2 notes, got %d", len(todoItem.Notes))
+	}
+
+	if todoItem.Notes[1].Text != "Vendor replied" {
+		t.Errorf("Expected notes in insertion order, got %v", todoItem.Notes)
+	}
+
+	if len(repo.todos[0].Notes) != 2 {
+		t.Errorf("Expected 2 saved notes, got %d", len(repo.todos[0].Notes))
+	}
+
+	// Test an empty note.
+	if _, err := service.AddNote(addedTodo.ID, "   "); err == nil {
+		t.Error("Expected error for empty note")
+	}
+
+	// Test a non-existent todo.
+	if _, err := service.AddNote(999, "Note"); err == nil {
+		t.Error("Expected error for non-existent todo")
+	}
+}
@@ -0,0 +1,77 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Priority represents the urgency of a todo item.
+type Priority int
+
+// Priority levels, from lowest to highest.
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = map[Priority]string{
+	PriorityNone:   "none",
+	PriorityLow:    "low",
+	PriorityMedium: "medium",
+	PriorityHigh:   "high",
+	PriorityUrgent: "urgent",
+}
+
+// String returns the name of the priority level.
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// ParsePriority converts a level name into a Priority.
+func ParsePriority(s string) (Priority, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "" {
+		return PriorityNone, nil
+	}
+	for p, n := range priorityNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("invalid priority %q (expected none, low, medium, high or urgent)", s)
+}
+
+// MarshalText encodes the priority as its level name.
+func (p Priority) MarshalText() ([]byte, error) {
+	if _, ok := priorityNames[p]; !ok {
+		return nil, fmt.Errorf("invalid priority %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a priority from its level name.
+func (p *Priority) UnmarshalText(text []byte) error {
+	parsed, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// FilterByPriority returns the todos with at least the given priority.
+func FilterByPriority(todos []Todo, minimum Priority) []Todo {
+	var filtered []Todo
+	for _, todo := range todos {
+		if todo.Priority >= minimum {
+			filtered = append(filtered, todo)
+		}
+	}
+	return filtered
+}
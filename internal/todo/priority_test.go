@@ -0,0 +1,89 @@
+package todo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Priority
+		wantErr  bool
+	}{
+		{name: "empty", input: "", expected: PriorityNone},
+		{name: "none", input: "none", expected: PriorityNone},
+		{name: "low", input: "low", expected: PriorityLow},
+		{name: "medium", input: "medium", expected: PriorityMedium},
+		{name: "high uppercase", input: "HIGH", expected: PriorityHigh},
+		{name: "urgent with spaces", input: " urgent ", expected: PriorityUrgent},
+		{name: "invalid", input: "critical", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParsePriority(tt.input)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected priority %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPriority_JSON(t *testing.T) {
+	data, err := json.Marshal(Todo{ID: 1, Description: "Test todo", Priority: PriorityHigh})
+	if err != nil {
+		t.Fatalf("Failed to marshal todo: %v", err)
+	}
+
+	var decoded Todo
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal todo: %v", err)
+	}
+
+	if decoded.Priority != PriorityHigh {
+		t.Errorf("Expected priority high, got %s", decoded.Priority)
+	}
+
+	// Todos written before priorities existed have no priority field.
+	var legacy Todo
+	if err := json.Unmarshal([]byte(`{"id":1,"description":"Old todo","completed":false}`), &legacy); err != nil {
+		t.Fatalf("Failed to unmarshal legacy todo: %v", err)
+	}
+
+	if legacy.Priority != PriorityNone {
+		t.Errorf("Expected priority none for legacy todo, got %s", legacy.Priority)
+	}
+}
+
+func TestFilterByPriority(t *testing.T) {
+	todos := []Todo{
+		{ID: 1, Priority: PriorityNone},
+		{ID: 2, Priority: PriorityHigh},
+		{ID: 3, Priority: PriorityMedium},
+		{ID: 4, Priority: PriorityUrgent},
+	}
+
+	filtered := FilterByPriority(todos, PriorityHigh)
+	if len(filtered) != 2 {
+		t.Fatalf("Expected 2 todos, got %d", len(filtered))
+	}
+
+	if filtered[0].ID != 2 || filtered[1].ID != 4 {
+		t.Errorf("Expected todos 2 and 4, got %d and %d", filtered[0].ID, filtered[1].ID)
+	}
+}
@@ -0,0 +1,282 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Project groups related todos.
+type Project struct {
+	Name       string     `json:"name"`
+	Archived   bool       `json:"archived"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+// ProjectStats holds the statistics of the todos in a single project.
+// An empty Project name groups the todos without a project.
+type ProjectStats struct {
+	Project string
+	Stats
+}
+
+// ProjectRepository is implemented by repositories that can persist projects.
+type ProjectRepository interface {
+	SaveProjects(projects []Project) error
+	LoadProjects() ([]Project, error)
+}
+
+// WithProject assigns a new todo to a project.
+func WithProject(name string) AddOption {
+	return func(t *Todo) {
+		t.Project = strings.TrimSpace(name)
+	}
+}
+
+// AddProject creates a new project.
+func (s *Service) AddProject(name string) (*Project, error) {
+	var project *Project
+	err := s.retry(func() error {
+		var err error
+		project, err = s.addProject(name)
+		return err
+	})
+	return project, err
+}
+
+// addProject is AddProject without retrying on conflicts.
+func (s *Service) addProject(name string) (*Project, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("project name cannot be empty")
+	}
+
+	if _, err := s.GetProject(name); err == nil {
+		return nil, fmt.Errorf("project %q already exists", name)
+	}
+
+	project := Project{
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+
+	s.projects = append(s.projects, project)
+
+	if err := s.saveProjects(); err != nil {
+		return nil, fmt.Errorf("failed to save project: %w", err)
+	}
+
+	return &project, nil
+}
+
+// GetProjects returns all projects sorted by name.
+func (s *Service) GetProjects() []Project {
+	projects := make([]Project, len(s.projects))
+	copy(projects, s.projects)
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Name < projects[j].Name
+	})
+	return projects
+}
+
+// GetProject returns a project by its name.
+func (s *Service) GetProject(name string) (*Project, error) {
+	for i, project := range s.projects {
+		if project.Name == name {
+			return &s.projects[i], nil
+		}
+	}
+	return nil, fmt.Errorf("project %q not found", name)
+}
+
+// RenameProject renames a project and moves its todos along.
+func (s *Service) RenameProject(oldName, newName string) error {
+	return s.retry(func() error {
+		return s.renameProject(oldName, newName)
+	})
+}
+
+// renameProject is RenameProject without retrying on conflicts.
+func (s *Service) renameProject(oldName, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("project name cannot be empty")
+	}
+
+	project, err := s.GetProject(oldName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.GetProject(newName); err == nil {
+		return fmt.Errorf("project %q already exists", newName)
+	}
+
+	todos, err := s.list(ListOptions{Project: oldName})
+	if err != nil {
+		return err
+	}
+
+	project.Name = newName
+	for _, todo := range todos {
+		s.markChanged(*todo)
+		todo.Project = newName
+	}
+
+	// The todos are saved first, so that a conflict is found before
+	// anything is written.
+	if err := s.save(ActionRenameProject); err != nil {
+		return err
+	}
+
+	if err := s.renameArchivedProject(oldName, newName); err != nil {
+		return err
+	}
+
+	return s.saveProjects()
+}
+
+// renameArchivedProject moves the archived todos in the old project to the
+// new one.
+func (s *Service) renameArchivedProject(oldName, newName string) error {
+	repo, ok := s.repo.(ArchiveRepository)
+	if !ok {
+		return nil
+	}
+
+	archived, err := s.GetArchived()
+	if err != nil {
+		return err
+	}
+
+	renamed := slices.Clone(archived)
+	changed := false
+	for i := range renamed {
+		if renamed[i].Project == oldName {
+			renamed[i].Project = newName
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := repo.SaveArchive(renamed); err != nil {
+		return fmt.Errorf("failed to save archive: %w", err)
+	}
+	s.archived = renamed
+	return nil
+}
+
+// ArchiveProject marks a project as archived so no new todos can be added to it.
+func (s *Service) ArchiveProject(name string) error {
+	return s.retry(func() error {
+		return s.archiveProject(name)
+	})
+}
+
+// archiveProject is ArchiveProject without retrying on conflicts.
+func (s *Service) archiveProject(name string) error {
+	project, err := s.GetProject(name)
+	if err != nil {
+		return err
+	}
+
+	if project.Archived {
+		return fmt.Errorf("project %q is already archived", name)
+	}
+
+	project.Archived = true
+	now := time.Now()
+	project.ArchivedAt = &now
+
+	return s.saveProjects()
+}
+
+// GetStatsByProject returns statistics per project, sorted by project name.
+// Todos without a project are grouped under an empty name, listed first.
+func (s *Service) GetStatsByProject() ([]ProjectStats, error) {
+	todos, err := s.List(ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	byProject := make(map[string]*ProjectStats)
+	for _, project := range s.projects {
+		byProject[project.Name] = &ProjectStats{Project: project.Name}
+	}
+
+	now := time.Now()
+	for _, todo := range todos {
+		stats, ok := byProject[todo.Project]
+		if !ok {
+			stats = &ProjectStats{Project: todo.Project}
+			byProject[todo.Project] = stats
+		}
+		stats.Total++
+		if todo.Completed {
+			stats.Completed++
+		} else {
+			stats.Pending++
+		}
+		if todo.IsOverdue(now) {
+			stats.Overdue++
+		}
+	}
+
+	result := make([]ProjectStats, 0, len(byProject))
+	for _, stats := range byProject {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Project < result[j].Project
+	})
+	return result, nil
+}
+
+func (s *Service) validateProject(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	project, err := s.GetProject(name)
+	if err != nil {
+		return err
+	}
+
+	if project.Archived {
+		return fmt.Errorf("project %q is archived", name)
+	}
+
+	return nil
+}
+
+func (s *Service) saveProjects() error {
+	repo, ok := s.repo.(ProjectRepository)
+	if !ok {
+		return fmt.Errorf("storage does not support projects")
+	}
+	if err := repo.SaveProjects(s.projects); err != nil {
+		return err
+	}
+	s.writes++
+	return nil
+}
+
+func (s *Service) loadProjects() error {
+	repo, ok := s.repo.(ProjectRepository)
+	if !ok {
+		return nil
+	}
+
+	projects, err := repo.LoadProjects()
+	if err != nil {
+		return err
+	}
+
+	s.projects = projects
+	return nil
+}
@@ -0,0 +1,222 @@
+package todo
+
+import (
+	"testing"
+)
+
+// MockProjectRepository is a mock Repository that also persists projects.
+type MockProjectRepository struct {
+	MockRepository
+	projects []Project
+}
+
+func (m *MockProjectRepository) SaveProjects(projects []Project) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.projects = make([]Project, len(projects))
+	copy(m.projects, projects)
+	return nil
+}
+
+func (m *MockProjectRepository) LoadProjects() ([]Project, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	result := make([]Project, len(m.projects))
+	copy(result, m.projects)
+	return result, nil
+}
+
+// MockProjectArchiveRepository is a MockProjectRepository that also stores
+// an archive.
+type MockProjectArchiveRepository struct {
+	MockProjectRepository
+	archive []Todo
+}
+
+func (m *MockProjectArchiveRepository) SaveArchive(todos []Todo) error {
+	m.archive = make([]Todo, len(todos))
+	copy(m.archive, todos)
+	return nil
+}
+
+func (m *MockProjectArchiveRepository) LoadArchive() ([]Todo, error) {
+	result := make([]Todo, len(m.archive))
+	copy(result, m.archive)
+	return result, nil
+}
+
+func TestService_AddProject(t *testing.T) {
+	repo := &MockProjectRepository{}
+	service := NewService(repo)
+
+	project, err := service.AddProject(" backend ")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if project.Name != "backend" {
+		t.Errorf("Expected project name backend, got %s", project.Name)
+	}
+
+	if len(repo.projects) != 1 {
+		t.Errorf("Expected 1 saved project, got %d", len(repo.projects))
+	}
+
+	// Test adding a duplicate project.
+	if _, err := service.AddProject("backend"); err == nil {
+		t.Error("Expected error for duplicate project")
+	}
+
+	// Test adding a project without a name.
+	if _, err := service.AddProject(""); err == nil {
+		t.Error("Expected error for empty project name")
+	}
+
+	// Projects are reloaded by a new service.
+	reloaded := NewService(repo)
+	if _, err := reloaded.GetProject("backend"); err != nil {
+		t.Errorf("Expected project to be reloaded: %v", err)
+	}
+}
+
+func TestService_AddProject_Unsupported(t *testing.T) {
+	service := NewService(&MockRepository{})
+
+	if _, err := service.AddProject("backend"); err == nil {
+		t.Error("Expected error when repository does not support projects")
+	}
+}
+
+func TestService_Add_WithProject(t *testing.T) {
+	repo := &MockProjectRepository{}
+	service := NewService(repo)
+
+	if _, err := service.Add("Orphan", WithProject("missing")); err == nil {
+		t.Error("Expected error for non-existent project")
+	}
+
+	if _, err := service.AddProject("backend"); err != nil {
+		t.Fatalf("Failed to add project: %v", err)
+	}
+
+	todoItem, err := service.Add("Add rate limiting", WithProject("backend"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if todoItem.Project != "backend" {
+		t.Errorf("Expected project backend, got %s", todoItem.Project)
+	}
+
+	if err := service.ArchiveProject("backend"); err != nil {
+		t.Fatalf("Failed to archive project: %v", err)
+	}
+
+	if _, err := service.Add("Too late", WithProject("backend")); err == nil {
+		t.Error("Expected error when adding to an archived project")
+	}
+
+	if err := service.ArchiveProject("backend"); err == nil {
+		t.Error("Expected error when archiving an archived project")
+	}
+}
+
+func TestService_RenameProject(t *testing.T) {
+	repo := &MockProjectArchiveRepository{
+		archive: []Todo{
+			{ID: 1, Description: "Add caching", Project: "backend", Completed: true},
+			{ID: 2, Description: "Add styles", Project: "frontend", Completed: true},
+		},
+	}
+	service := NewService(repo)
+
+	for _, name := range []string{"backend", "frontend"} {
+		if _, err := service.AddProject(name); err != nil {
+			t.Fatalf("Failed to add project: %v", err)
+		}
+	}
+
+	todoItem, err := service.Add("Add rate limiting", WithProject("backend"))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if err := service.RenameProject("backend", "frontend"); err == nil {
+		t.Error("Expected error when renaming to an existing project")
+	}
+
+	if err := service.RenameProject("backend", "api"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := service.GetProject("backend"); err == nil {
+		t.Error("Expected old project name to be gone")
+	}
+
+	renamed, err := service.Get(todoItem.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if renamed.Project != "api" {
+		t.Errorf("Expected todo to move to project api, got %s", renamed.Project)
+	}
+
+	if repo.archive[0].Project != "api" || repo.archive[1].Project != "frontend" {
+		t.Errorf("Expected archived todo to move to project api, got %+v", repo.archive)
+	}
+
+	if err := service.RenameProject("missing", "other"); err == nil {
+		t.Error("Expected error for non-existent project")
+	}
+}
+
+func TestService_GetStatsByProject(t *testing.T) {
+	repo := &MockProjectRepository{}
+	service := NewService(repo)
+
+	for _, name := range []string{"backend", "docs"} {
+		if _, err := service.AddProject(name); err != nil {
+			t.Fatalf("Failed to add project: %v", err)
+		}
+	}
+
+	first, err := service.Add("First", WithProject("backend"))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if _, err := service.Add("Second", WithProject("backend")); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if _, err := service.Add("Loose"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if err := service.Complete(first.ID); err != nil {
+		t.Fatalf("Failed to complete todo: %v", err)
+	}
+
+	stats, err := service.GetStatsByProject()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("Expected 3 project stats, got %d", len(stats))
+	}
+
+	if stats[0].Project != "" || stats[0].Total != 1 {
+		t.Errorf("Expected 1 todo without project, got %+v", stats[0])
+	}
+
+	if stats[1].Project != "backend" || stats[1].Total != 2 || stats[1].CompletionRate() != 50 {
+		t.Errorf("Expected backend with 2 todos at 50%%, got %+v", stats[1])
+	}
+
+	if stats[2].Project != "docs" || stats[2].Total != 0 {
+		t.Errorf("Expected empty docs project, got %+v", stats[2])
+	}
+}
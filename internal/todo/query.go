@@ -0,0 +1,555 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryError reports an invalid query and the 1-based column where the
+// problem was found.
+type QueryError struct {
+	Column  int
+	Message string
+}
+
+// Error implements the error interface.
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at column %d: %s", e.Column, e.Message)
+}
+
+// Query is a parsed filter expression such as
+// "status:pending and (tag:infra or priority>=high) and due<+7d".
+//
+// Expressions combine conditions with "and", "or", "not" and parentheses;
+// adjacent conditions are joined with "and". A condition is either a
+// comparison "field op value" or a bare word, which matches the description.
+// Supported fields are status, priority, due, created, completed, updated,
+// tag, project, id, parent, desc and text; operators are ":", "=", "!=",
+// "<", "<=", ">" and ">=". Dates accept everything ParseDate does, as well as
+// "none" with ":", "=" and "!=".
+type Query struct {
+	source string
+	root   queryNode
+}
+
+// queryEnv holds what conditions need besides the todo itself.
+type queryEnv struct {
+	now     time.Time
+	blocked func(Todo) bool
+}
+
+type queryNode interface {
+	match(todo Todo, env queryEnv) bool
+}
+
+// ParseQuery parses a filter expression. Relative dates are resolved
+// against now.
+func ParseQuery(source string, now time.Time) (*Query, error) {
+	tokens, err := lexQuery(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens, now: now, end: len([]rune(source)) + 1}
+	if len(tokens) == 0 {
+		return nil, &QueryError{Column: 1, Message: "query is empty"}
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, ok := p.peek(); ok {
+		return nil, &QueryError{Column: tok.column, Message: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+
+	return &Query{source: source, root: root}, nil
+}
+
+// String returns the source of the query.
+func (q *Query) String() string {
+	return q.source
+}
+
+// Query returns the todos matching a filter expression; see Query for the
+// syntax.
+func (s *Service) Query(expression string) ([]Todo, error) {
+	query, err := ParseQuery(expression, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	todos, err := s.List(ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return s.FilterQuery(todos, query)
+}
+
+// FilterQuery returns the todos matching a parsed query.
+func (s *Service) FilterQuery(todos []Todo, query *Query) ([]Todo, error) {
+	// The blockers are only read for queries that ask for them.
+	var readErr error
+	env := queryEnv{
+		now: time.Now(),
+		blocked: func(todo Todo) bool {
+			blockers, err := s.OpenBlockers(todo)
+			if err != nil && readErr == nil {
+				readErr = err
+			}
+			return len(blockers) > 0
+		},
+	}
+
+	var filtered []Todo
+	for _, todo := range todos {
+		if query.root.match(todo, env) {
+			filtered = append(filtered, todo)
+		}
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return filtered, nil
+}
+
+// Lexer.
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type queryToken struct {
+	kind   tokenKind
+	text   string
+	column int
+}
+
+func isOperatorRune(r rune) bool {
+	return r == ':' || r == '=' || r == '!' || r == '<' || r == '>'
+}
+
+func lexQuery(source string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		column := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenLParen, text: "(", column: column})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenRParen, text: ")", column: column})
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &QueryError{Column: column, Message: "unterminated string"}
+			}
+			tokens = append(tokens, queryToken{kind: tokenString, text: string(runes[i+1 : end]), column: column})
+			i = end + 1
+		case isOperatorRune(r):
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' && r != ':' && r != '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &QueryError{Column: column, Message: `expected "!="`}
+			}
+			tokens = append(tokens, queryToken{kind: tokenOperator, text: op, column: column})
+			i += len(op)
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !isOperatorRune(runes[end]) &&
+				runes[end] != '(' && runes[end] != ')' && runes[end] != '"' && runes[end] != '\'' {
+				end++
+			}
+			tokens = append(tokens, queryToken{kind: tokenWord, text: string(runes[i:end]), column: column})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parser.
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	now    time.Time
+	// end is the column just past the end of the source.
+	end int
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) next() (queryToken, bool) {
+	tok, ok := p.peek()
+	if ok {
+		p.pos++
+	}
+	return tok, ok
+}
+
+func isKeyword(tok queryToken, keyword string) bool {
+	return tok.kind == tokenWord && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, ok := p.peek()
+		if !ok || !isKeyword(tok, "or") {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokenRParen || isKeyword(tok, "or") {
+			return left, nil
+		}
+		if isKeyword(tok, "and") {
+			p.pos++
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	tok, ok := p.peek()
+	if ok && isKeyword(tok, "not") {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok, ok := p.next()
+	if !ok {
+		return nil, &QueryError{Column: p.end, Message: "unexpected end of query"}
+	}
+
+	switch tok.kind {
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, ok := p.next()
+		if !ok {
+			return nil, &QueryError{Column: p.end, Message: fmt.Sprintf("missing \")\" for \"(\" at column %d", tok.column)}
+		}
+		if closing.kind != tokenRParen {
+			return nil, &QueryError{Column: closing.column, Message: fmt.Sprintf("expected \")\", got %q", closing.text)}
+		}
+		return node, nil
+	case tokenString:
+		return textNode{text: strings.ToLower(tok.text)}, nil
+	case tokenWord:
+		if isKeyword(tok, "and") || isKeyword(tok, "or") {
+			return nil, &QueryError{Column: tok.column, Message: fmt.Sprintf("unexpected %q", tok.text)}
+		}
+		if op, ok := p.peek(); ok && op.kind == tokenOperator {
+			p.pos++
+			return p.parseComparison(tok, op)
+		}
+		return textNode{text: strings.ToLower(tok.text)}, nil
+	default:
+		return nil, &QueryError{Column: tok.column, Message: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+}
+
+func (p *queryParser) parseComparison(field, op queryToken) (queryNode, error) {
+	value, ok := p.next()
+	if !ok {
+		return nil, &QueryError{Column: p.end, Message: fmt.Sprintf("missing value after %q", field.text+op.text)}
+	}
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, &QueryError{Column: value.column, Message: fmt.Sprintf("expected a value, got %q", value.text)}
+	}
+
+	equality := op.text == ":" || op.text == "=" || op.text == "!="
+	requireEquality := func() error {
+		if !equality {
+			return &QueryError{Column: op.column, Message: fmt.Sprintf("field %q does not support %q", field.text, op.text)}
+		}
+		return nil
+	}
+	invalid := func(format string, args ...any) error {
+		return &QueryError{Column: value.column, Message: fmt.Sprintf(format, args...)}
+	}
+
+	name := strings.ToLower(field.text)
+	switch name {
+	case "status", "is":
+		if err := requireEquality(); err != nil {
+			return nil, err
+		}
+		status := strings.ToLower(value.text)
+		switch status {
+		case "pending", "open", "completed", "done", "overdue", "blocked", "ready", "recurring":
+		default:
+			return nil, invalid("invalid status %q (expected pending, completed, overdue, blocked, ready or recurring)", value.text)
+		}
+		return negate(op.text, statusNode{status: status}), nil
+
+	case "priority":
+		priority, err := ParsePriority(value.text)
+		if err != nil {
+			return nil, invalid("%v", err)
+		}
+		return compareNode{op: op.text, value: func(t Todo) (int, bool) { return int(t.Priority), true }, target: int(priority)}, nil
+
+	case "id", "parent":
+		number, err := strconv.Atoi(value.text)
+		if err != nil {
+			return nil, invalid("invalid number %q", value.text)
+		}
+		get := func(t Todo) (int, bool) { return t.ID, true }
+		if name == "parent" {
+			get = func(t Todo) (int, bool) { return t.ParentID, true }
+		}
+		return compareNode{op: op.text, value: get, target: number}, nil
+
+	case "due", "created", "completed", "updated":
+		get := dateField(name)
+		if strings.EqualFold(value.text, "none") {
+			if err := requireEquality(); err != nil {
+				return nil, err
+			}
+			return negate(op.text, noDateNode{value: get}), nil
+		}
+		date, err := ParseDate(value.text, p.now)
+		if err != nil {
+			return nil, invalid("%v", err)
+		}
+		return dateNode{op: op.text, value: get, target: date}, nil
+
+	case "tag":
+		if err := requireEquality(); err != nil {
+			return nil, err
+		}
+		return negate(op.text, tagNode{tag: NormalizeTag(value.text)}), nil
+
+	case "project":
+		if err := requireEquality(); err != nil {
+			return nil, err
+		}
+		project := value.text
+		if value.kind == tokenWord && strings.EqualFold(project, "none") {
+			project = ""
+		}
+		return negate(op.text, projectNode{project: project}), nil
+
+	case "desc", "description", "text":
+		if err := requireEquality(); err != nil {
+			return nil, err
+		}
+		return negate(op.text, textNode{text: strings.ToLower(value.text), everywhere: name == "text"}), nil
+
+	default:
+		return nil, &QueryError{Column: field.column, Message: fmt.Sprintf("unknown field %q", field.text)}
+	}
+}
+
+func negate(op string, node queryNode) queryNode {
+	if op == "!=" {
+		return notNode{node}
+	}
+	return node
+}
+
+func dateField(name string) func(Todo) *time.Time {
+	switch name {
+	case "due":
+		return func(t Todo) *time.Time { return t.DueAt }
+	case "created":
+		return func(t Todo) *time.Time { return &t.CreatedAt }
+	case "completed":
+		return func(t Todo) *time.Time { return t.CompletedAt }
+	default:
+		return func(t Todo) *time.Time { return t.UpdatedAt }
+	}
+}
+
+// Nodes.
+
+type andNode struct{ left, right queryNode }
+
+func (n andNode) match(t Todo, env queryEnv) bool {
+	return n.left.match(t, env) && n.right.match(t, env)
+}
+
+type orNode struct{ left, right queryNode }
+
+func (n orNode) match(t Todo, env queryEnv) bool {
+	return n.left.match(t, env) || n.right.match(t, env)
+}
+
+type notNode struct{ operand queryNode }
+
+func (n notNode) match(t Todo, env queryEnv) bool {
+	return !n.operand.match(t, env)
+}
+
+type statusNode struct{ status string }
+
+func (n statusNode) match(t Todo, env queryEnv) bool {
+	switch n.status {
+	case "pending", "open":
+		return !t.Completed
+	case "completed", "done":
+		return t.Completed
+	case "overdue":
+		return t.IsOverdue(env.now)
+	case "blocked":
+		return !t.Completed && env.blocked(t)
+	case "ready":
+		return !t.Completed && !env.blocked(t)
+	case "recurring":
+		return t.Recurrence != nil
+	}
+	return false
+}
+
+type compareNode struct {
+	op     string
+	value  func(Todo) (int, bool)
+	target int
+}
+
+func (n compareNode) match(t Todo, _ queryEnv) bool {
+	value, ok := n.value(t)
+	return ok && compareInts(n.op, value, n.target)
+}
+
+type dateNode struct {
+	op     string
+	value  func(Todo) *time.Time
+	target time.Time
+}
+
+// match compares dates by calendar day.
+func (n dateNode) match(t Todo, _ queryEnv) bool {
+	value := n.value(t)
+	if value == nil {
+		return false
+	}
+	day := StartOfDay(value.In(n.target.Location()))
+	return compareInts(n.op, day.Compare(n.target), 0)
+}
+
+type noDateNode struct{ value func(Todo) *time.Time }
+
+func (n noDateNode) match(t Todo, _ queryEnv) bool {
+	return n.value(t) == nil
+}
+
+type tagNode struct{ tag string }
+
+func (n tagNode) match(t Todo, _ queryEnv) bool {
+	return t.HasTag(n.tag)
+}
+
+type projectNode struct{ project string }
+
+func (n projectNode) match(t Todo, _ queryEnv) bool {
+	return t.Project == n.project
+}
+
+// textNode matches a case-insensitive substring of the description, or of
+// the description, notes and tags when everywhere is set.
+type textNode struct {
+	text       string
+	everywhere bool
+}
+
+func (n textNode) match(t Todo, _ queryEnv) bool {
+	if strings.Contains(strings.ToLower(t.Description), n.text) {
+		return true
+	}
+	if !n.everywhere {
+		return false
+	}
+	for _, note := range t.Notes {
+		if strings.Contains(strings.ToLower(note.Text), n.text) {
+			return true
+		}
+	}
+	for _, tag := range t.Tags {
+		if strings.Contains(tag, n.text) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareInts(op string, a, b int) bool {
+	switch op {
+	case ":", "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
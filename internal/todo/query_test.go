@@ -0,0 +1,121 @@
+package todo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery_Errors(t *testing.T) {
+	now := time.Date(2024, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		query  string
+		column int
+	}{
+		{name: "empty", query: "   ", column: 1},
+		{name: "unknown field", query: "status:pending and colour:red", column: 20},
+		{name: "invalid status", query: "status:maybe", column: 8},
+		{name: "invalid priority", query: "priority>=huge", column: 11},
+		{name: "invalid date", query: "due<someday", column: 5},
+		{name: "unsupported operator", query: "tag<infra", column: 4},
+		{name: "missing value", query: "priority>=", column: 11},
+		{name: "missing closing paren", query: "(tag:a or tag:b", column: 16},
+		{name: "unexpected closing paren", query: "tag:a)", column: 6},
+		{name: "dangling or", query: "tag:a or", column: 9},
+		{name: "leading and", query: "and tag:a", column: 1},
+		{name: "lone bang", query: "tag!a", column: 4},
+		{name: "unterminated string", query: `desc:"oops`, column: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuery(tt.query, now)
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+
+			var queryErr *QueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("Expected a QueryError, got %T: %v", err, err)
+			}
+
+			if queryErr.Column != tt.column {
+				t.Errorf("Expected column %d, got %d (%v)", tt.column, queryErr.Column, err)
+			}
+		})
+	}
+}
+
+func TestService_Query(t *testing.T) {
+	repo := &MockProjectRepository{}
+	service := NewService(repo)
+
+	if _, err := service.AddProject("backend"); err != nil {
+		t.Fatalf("Failed to add project: %v", err)
+	}
+
+	today := StartOfDay(time.Now())
+	infra, _ := service.Add("Rotate certificates", WithTags("infra"), WithDueAt(today.AddDate(0, 0, 2)))
+	urgent, _ := service.Add("Fix login", WithPriority(PriorityUrgent), WithProject("backend"), WithDueAt(today.AddDate(0, 0, 30)))
+	done, _ := service.Add("Write docs", WithTags("docs"), WithPriority(PriorityHigh))
+	blocked, _ := service.Add("Release", WithDueAt(today.AddDate(0, 0, -1)))
+
+	if err := service.Complete(done.ID); err != nil {
+		t.Fatalf("Failed to complete todo: %v", err)
+	}
+	if err := service.Block(blocked.ID, infra.ID); err != nil {
+		t.Fatalf("Failed to block todo: %v", err)
+	}
+	if _, err := service.AddNote(urgent.ID, "Customer escalation"); err != nil {
+		t.Fatalf("Failed to add note: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []int
+	}{
+		{name: "example", query: "status:pending and (tag:infra or priority>=high) and due<+7d", expected: []int{infra.ID}},
+		{name: "status completed", query: "status:done", expected: []int{done.ID}},
+		{name: "priority comparison", query: "priority>=high", expected: []int{urgent.ID, done.ID}},
+		{name: "priority equality", query: "priority=none", expected: []int{infra.ID, blocked.ID}},
+		{name: "implicit and", query: "status:pending priority:urgent", expected: []int{urgent.ID}},
+		{name: "or", query: "tag:docs or project:backend", expected: []int{urgent.ID, done.ID}},
+		{name: "not", query: "not tag:infra and status:pending", expected: []int{urgent.ID, blocked.ID}},
+		{name: "not equal", query: "project!=backend and tag!=docs", expected: []int{infra.ID, blocked.ID}},
+		{name: "project none", query: "project:none", expected: []int{infra.ID, done.ID, blocked.ID}},
+		{name: "due none", query: "due:none", expected: []int{done.ID}},
+		{name: "due today", query: "due:today", expected: nil},
+		{name: "due range", query: "due>=today and due<=+30d", expected: []int{infra.ID, urgent.ID}},
+		{name: "overdue", query: "status:overdue", expected: []int{blocked.ID}},
+		{name: "blocked", query: "status:blocked", expected: []int{blocked.ID}},
+		{name: "ready", query: "status:ready", expected: []int{infra.ID, urgent.ID}},
+		{name: "bare word", query: "release", expected: []int{blocked.ID}},
+		{name: "quoted phrase", query: `"fix login"`, expected: []int{urgent.ID}},
+		{name: "text searches notes", query: "text:escalation", expected: []int{urgent.ID}},
+		{name: "desc ignores notes", query: "desc:escalation", expected: nil},
+		{name: "id comparison", query: "id>2", expected: []int{done.ID, blocked.ID}},
+		{name: "case-insensitive keywords", query: "TAG:infra OR Tag:docs", expected: []int{infra.ID, done.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todos, err := service.Query(tt.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var ids []int
+			for _, todoItem := range todos {
+				ids = append(ids, todoItem.ID)
+			}
+
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected IDs %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the period of a recurrence rule.
+type Frequency string
+
+// Supported recurrence frequencies.
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+	FrequencyYearly  Frequency = "YEARLY"
+)
+
+// maxRecurrencePeriods bounds the search for the next occurrence so rules
+// that can never match (e.g. the 31st of every other February) terminate.
+const maxRecurrencePeriods = 1000
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Recurrence is a schedule following a subset of RFC 5545 RRULE: FREQ,
+// INTERVAL, BYDAY (without ordinals), BYMONTHDAY and UNTIL.
+type Recurrence struct {
+	Frequency  Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Until      *time.Time
+
+	// untilLayout is the RRULE layout UNTIL was written in. Until holds the
+	// wall clock of a floating or date-only UNTIL, which Next reads in the
+	// anchor's time zone.
+	untilLayout string
+}
+
+// RRULE UNTIL layouts: a UTC time, a floating local time and a date.
+const (
+	untilUTCLayout      = "20060102T150405Z"
+	untilFloatingLayout = "20060102T150405"
+	untilDateLayout     = "20060102"
+)
+
+// ParseRecurrence reads a schedule from a shorthand ("daily", "weekday",
+// "weekly", "weekly mon,thu", "monthly", "monthly 1", "monthly -1",
+// "yearly") or an RRULE such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO".
+func ParseRecurrence(input string) (Recurrence, error) {
+	text := strings.TrimSpace(input)
+	if text == "" {
+		return Recurrence{}, fmt.Errorf("recurrence cannot be empty")
+	}
+
+	if strings.Contains(text, "=") {
+		return parseRRule(text)
+	}
+
+	fields := strings.Fields(strings.ToLower(text))
+	rule := Recurrence{Interval: 1}
+	switch fields[0] {
+	case "daily":
+		rule.Frequency = FrequencyDaily
+	case "weekday", "weekdays":
+		rule.Frequency = FrequencyWeekly
+		rule.ByDay = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	case "weekly":
+		rule.Frequency = FrequencyWeekly
+		if len(fields) > 1 {
+			for _, name := range strings.Split(strings.Join(fields[1:], ","), ",") {
+				if name == "" {
+					continue
+				}
+				weekday, err := parseWeekday(name)
+				if err != nil {
+					return Recurrence{}, err
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+			fields = fields[:1]
+		}
+	case "monthly":
+		rule.Frequency = FrequencyMonthly
+		if len(fields) > 1 {
+			day, err := parseMonthDay(fields[1])
+			if err != nil {
+				return Recurrence{}, err
+			}
+			rule.ByMonthDay = []int{day}
+			fields = fields[:1]
+		}
+	case "yearly":
+		rule.Frequency = FrequencyYearly
+	default:
+		return Recurrence{}, fmt.Errorf("invalid recurrence %q (expected daily, weekday, weekly [days], monthly [day], yearly or an RRULE)", input)
+	}
+
+	if len(fields) > 1 {
+		return Recurrence{}, fmt.Errorf("invalid recurrence %q: unexpected %q", input, strings.Join(fields[1:], " "))
+	}
+
+	return rule, nil
+}
+
+func parseRRule(text string) (Recurrence, error) {
+	rule := Recurrence{Interval: 1}
+	body := strings.TrimPrefix(strings.ToUpper(text), "RRULE:")
+
+	for _, part := range strings.Split(body, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Recurrence{}, fmt.Errorf("invalid RRULE part %q", part)
+		}
+
+		switch key {
+		case "FREQ":
+			switch Frequency(value) {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+				rule.Frequency = Frequency(value)
+			default:
+				return Recurrence{}, fmt.Errorf("unsupported RRULE frequency %q", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return Recurrence{}, fmt.Errorf("invalid RRULE interval %q", value)
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				weekday, err := parseWeekday(code)
+				if err != nil {
+					return Recurrence{}, err
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, text := range strings.Split(value, ",") {
+				day, err := parseMonthDay(text)
+				if err != nil {
+					return Recurrence{}, err
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		case "UNTIL":
+			until, layout, err := parseUntil(value)
+			if err != nil {
+				return Recurrence{}, err
+			}
+			rule.Until = &until
+			rule.untilLayout = layout
+		case "WKST":
+			// Weeks always start on Monday.
+		default:
+			return Recurrence{}, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+	}
+
+	if rule.Frequency == "" {
+		return Recurrence{}, fmt.Errorf("RRULE %q is missing FREQ", text)
+	}
+
+	if rule.Frequency == FrequencyYearly && (len(rule.ByDay) > 0 || len(rule.ByMonthDay) > 0) {
+		return Recurrence{}, fmt.Errorf("BYDAY and BYMONTHDAY are not supported with FREQ=YEARLY")
+	}
+
+	return rule, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	text := strings.ToLower(strings.TrimSpace(name))
+	for i, code := range weekdayCodes {
+		weekday := time.Weekday(i)
+		full := strings.ToLower(weekday.String())
+		if text == strings.ToLower(code) || text == full[:3] || text == full {
+			return weekday, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+func parseMonthDay(text string) (int, error) {
+	day, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || day == 0 || day < -31 || day > 31 {
+		return 0, fmt.Errorf("invalid day of month %q (expected 1 to 31, or -1 for the last day)", text)
+	}
+	return day, nil
+}
+
+// parseUntil returns the UNTIL time and the layout it was written in. A
+// floating or date-only UNTIL has no time zone yet, so its wall clock is
+// returned in UTC until Next reads it in the anchor's.
+func parseUntil(value string) (time.Time, string, error) {
+	for _, layout := range []string{untilUTCLayout, untilFloatingLayout, untilDateLayout} {
+		if until, err := time.Parse(layout, value); err == nil {
+			return until, layout, nil
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("invalid RRULE UNTIL %q", value)
+}
+
+// end returns the instant the rule ends: occurrences fall before it. A
+// floating or date-only UNTIL is read in loc, and a date-only UNTIL includes
+// the whole day.
+func (r Recurrence) end(loc *time.Location) time.Time {
+	until := *r.Until
+	switch r.untilLayout {
+	case untilFloatingLayout:
+		until = time.Date(until.Year(), until.Month(), until.Day(), until.Hour(), until.Minute(), until.Second(), 0, loc)
+	case untilDateLayout:
+		return time.Date(until.Year(), until.Month(), until.Day()+1, 0, 0, 0, 0, loc)
+	}
+	return until.Add(time.Nanosecond)
+}
+
+// String returns the rule in RRULE notation.
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, weekday := range r.ByDay {
+			codes[i] = weekdayCodes[weekday]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Until != nil {
+		until := r.Until.UTC().Format(untilUTCLayout)
+		if r.untilLayout == untilFloatingLayout || r.untilLayout == untilDateLayout {
+			until = r.Until.Format(r.untilLayout)
+		}
+		parts = append(parts, "UNTIL="+until)
+	}
+	return strings.Join(parts, ";")
+}
+
+// MarshalText encodes the rule in RRULE notation.
+func (r Recurrence) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes a rule from RRULE notation.
+func (r *Recurrence) UnmarshalText(text []byte) error {
+	parsed, err := ParseRecurrence(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Next returns the first occurrence strictly after the given time. The anchor
+// is the date the series is aligned to: it fixes the weekday, day of month or
+// date used when the rule does not specify one, and the first period counted
+// by INTERVAL. It returns false when the rule has ended. A floating or
+// date-only UNTIL is read in the anchor's time zone.
+func (r Recurrence) Next(anchor, after time.Time) (time.Time, bool) {
+	anchor = StartOfDay(anchor)
+	var end time.Time
+	if r.Until != nil {
+		end = r.end(anchor.Location())
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	for period := 0; period < maxRecurrencePeriods*interval; period += interval {
+		for _, candidate := range r.candidates(anchor, period) {
+			if !candidate.After(after) {
+				continue
+			}
+			if r.Until != nil && !candidate.Before(end) {
+				return time.Time{}, false
+			}
+			return candidate, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// candidates returns the occurrences in the period that is the given number
+// of periods after the anchor's period, in chronological order.
+func (r Recurrence) candidates(anchor time.Time, period int) []time.Time {
+	var days []time.Time
+	switch r.Frequency {
+	case FrequencyDaily:
+		day := anchor.AddDate(0, 0, period)
+		if r.matchesDay(day) {
+			days = append(days, day)
+		}
+	case FrequencyWeekly:
+		// Weeks start on Monday.
+		offset := (int(anchor.Weekday()) + 6) % 7
+		monday := anchor.AddDate(0, 0, 7*period-offset)
+		for i := 0; i < 7; i++ {
+			day := monday.AddDate(0, 0, i)
+			if r.matchesWeekday(day, anchor) {
+				days = append(days, day)
+			}
+		}
+	case FrequencyMonthly:
+		first := time.Date(anchor.Year(), anchor.Month()+time.Month(period), 1, 0, 0, 0, 0, anchor.Location())
+		length := first.AddDate(0, 1, -1).Day()
+		switch {
+		case len(r.ByMonthDay) > 0:
+			for _, monthDay := range r.ByMonthDay {
+				if monthDay < 0 {
+					monthDay = length + monthDay + 1
+				}
+				if monthDay >= 1 && monthDay <= length {
+					days = append(days, first.AddDate(0, 0, monthDay-1))
+				}
+			}
+		case len(r.ByDay) > 0:
+			for i := 0; i < length; i++ {
+				day := first.AddDate(0, 0, i)
+				if r.matchesDay(day) {
+					days = append(days, day)
+				}
+			}
+		case anchor.Day() <= length:
+			days = append(days, first.AddDate(0, 0, anchor.Day()-1))
+		}
+	case FrequencyYearly:
+		day := time.Date(anchor.Year()+period, anchor.Month(), anchor.Day(), 0, 0, 0, 0, anchor.Location())
+		// Skip years without the anchor date, e.g. February 29th.
+		if day.Day() == anchor.Day() {
+			days = append(days, day)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+	return days
+}
+
+func (r Recurrence) matchesDay(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, weekday := range r.ByDay {
+		if day.Weekday() == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Recurrence) matchesWeekday(day, anchor time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return day.Weekday() == anchor.Weekday()
+	}
+	return r.matchesDay(day)
+}
@@ -0,0 +1,188 @@
+package todo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "daily", input: "daily", expected: "FREQ=DAILY"},
+		{name: "weekday", input: "weekday", expected: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{name: "weekly", input: "weekly", expected: "FREQ=WEEKLY"},
+		{name: "weekly with day", input: "weekly mon", expected: "FREQ=WEEKLY;BYDAY=MO"},
+		{name: "weekly with days", input: "Weekly tuesday,THU", expected: "FREQ=WEEKLY;BYDAY=TU,TH"},
+		{name: "monthly", input: "monthly", expected: "FREQ=MONTHLY"},
+		{name: "monthly with day", input: "monthly 1", expected: "FREQ=MONTHLY;BYMONTHDAY=1"},
+		{name: "monthly last day", input: "monthly -1", expected: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{name: "yearly", input: "yearly", expected: "FREQ=YEARLY"},
+		{name: "rrule", input: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", expected: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{name: "rrule with until", input: "freq=daily;until=20240401", expected: "FREQ=DAILY;UNTIL=20240401"},
+		{name: "rrule with floating until", input: "FREQ=DAILY;UNTIL=20240401T090000", expected: "FREQ=DAILY;UNTIL=20240401T090000"},
+		{name: "rrule with utc until", input: "FREQ=DAILY;UNTIL=20240401T090000Z", expected: "FREQ=DAILY;UNTIL=20240401T090000Z"},
+		{name: "empty", input: "", wantErr: true},
+		{name: "unknown shorthand", input: "hourly", wantErr: true},
+		{name: "invalid weekday", input: "weekly funday", wantErr: true},
+		{name: "invalid month day", input: "monthly 32", wantErr: true},
+		{name: "trailing words", input: "daily please", wantErr: true},
+		{name: "missing freq", input: "INTERVAL=2", wantErr: true},
+		{name: "unsupported part", input: "FREQ=DAILY;COUNT=3", wantErr: true},
+		{name: "invalid interval", input: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrence(tt.input)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if rule.String() != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, rule)
+			}
+		})
+	}
+}
+
+func TestRecurrence_Next(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		anchor   time.Time
+		after    time.Time
+		expected time.Time
+		ended    bool
+	}{
+		// 2024-03-15 is a Friday.
+		{name: "daily", rule: "daily", anchor: date(2024, 3, 15), after: date(2024, 3, 15), expected: date(2024, 3, 16)},
+		{name: "every other day", rule: "FREQ=DAILY;INTERVAL=2", anchor: date(2024, 3, 15), after: date(2024, 3, 15), expected: date(2024, 3, 17)},
+		{name: "weekday skips weekend", rule: "weekday", anchor: date(2024, 3, 15), after: date(2024, 3, 15), expected: date(2024, 3, 18)},
+		{name: "weekly keeps weekday", rule: "weekly", anchor: date(2024, 3, 15), after: date(2024, 3, 15), expected: date(2024, 3, 22)},
+		{name: "weekly on monday", rule: "weekly mon", anchor: date(2024, 3, 15), after: date(2024, 3, 15), expected: date(2024, 3, 18)},
+		{name: "biweekly", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", anchor: date(2024, 3, 18), after: date(2024, 3, 18), expected: date(2024, 4, 1)},
+		{name: "monthly on the first", rule: "monthly 1", anchor: date(2024, 3, 1), after: date(2024, 3, 1), expected: date(2024, 4, 1)},
+		{name: "monthly last day", rule: "monthly -1", anchor: date(2024, 1, 31), after: date(2024, 1, 31), expected: date(2024, 2, 29)},
+		{name: "monthly skips short months", rule: "monthly", anchor: date(2024, 1, 31), after: date(2024, 1, 31), expected: date(2024, 3, 31)},
+		{name: "yearly", rule: "yearly", anchor: date(2024, 3, 15), after: date(2024, 3, 15), expected: date(2025, 3, 15)},
+		{name: "skips missed occurrences", rule: "weekly", anchor: date(2024, 3, 1), after: date(2024, 3, 20), expected: date(2024, 3, 22)},
+		{name: "until reached", rule: "FREQ=DAILY;UNTIL=20240316", anchor: date(2024, 3, 15), after: date(2024, 3, 16), ended: true},
+		{name: "until date included", rule: "FREQ=DAILY;UNTIL=20240316", anchor: date(2024, 3, 15), after: date(2024, 3, 15), expected: date(2024, 3, 16)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("Failed to parse rule: %v", err)
+			}
+
+			next, ok := rule.Next(tt.anchor, tt.after)
+
+			if tt.ended {
+				if ok {
+					t.Errorf("Expected the rule to have ended, got %v", next)
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatal("Expected an occurrence but the rule ended")
+			}
+
+			if !next.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, next)
+			}
+		})
+	}
+}
+
+func TestRecurrence_Next_LocalUntil(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2024, month, day, 0, 0, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		after    time.Time
+		expected time.Time
+		ended    bool
+	}{
+		{name: "date includes its last day", rule: "FREQ=DAILY;UNTIL=20240131", after: date(1, 30), expected: date(1, 31)},
+		{name: "date ends after its last day", rule: "FREQ=DAILY;UNTIL=20240131", after: date(1, 31), ended: true},
+		{name: "floating time is local", rule: "FREQ=DAILY;UNTIL=20240131T000000", after: date(1, 30), expected: date(1, 31)},
+		{name: "floating time ends locally", rule: "FREQ=DAILY;UNTIL=20240130T235959", after: date(1, 30), ended: true},
+		// Midnight in New York is 05:00 UTC.
+		{name: "utc time is absolute", rule: "FREQ=DAILY;UNTIL=20240131T040000Z", after: date(1, 30), ended: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("Failed to parse rule: %v", err)
+			}
+
+			next, ok := rule.Next(date(1, 1), tt.after)
+
+			if tt.ended {
+				if ok {
+					t.Errorf("Expected the rule to have ended, got %v", next)
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatal("Expected an occurrence but the rule ended")
+			}
+
+			if !next.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, next)
+			}
+		})
+	}
+}
+
+func TestRecurrence_JSON(t *testing.T) {
+	rule, err := ParseRecurrence("weekly mon")
+	if err != nil {
+		t.Fatalf("Failed to parse rule: %v", err)
+	}
+
+	data, err := json.Marshal(Todo{ID: 1, Description: "Handover", Recurrence: &rule})
+	if err != nil {
+		t.Fatalf("Failed to marshal todo: %v", err)
+	}
+
+	var decoded Todo
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal todo: %v", err)
+	}
+
+	if decoded.Recurrence == nil || decoded.Recurrence.String() != "FREQ=WEEKLY;BYDAY=MO" {
+		t.Errorf("Expected recurrence FREQ=WEEKLY;BYDAY=MO, got %v", decoded.Recurrence)
+	}
+}
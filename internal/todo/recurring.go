@@ -0,0 +1,161 @@
+package todo
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithRecurrence makes a new todo the first instance of a recurring series.
+// Without a due date, it is due on the first occurrence from today on.
+func WithRecurrence(rule Recurrence) AddOption {
+	return func(t *Todo) {
+		t.Recurrence = &rule
+	}
+}
+
+// GetRecurringSeries returns the open instance of every active series.
+func (s *Service) GetRecurringSeries() ([]Todo, error) {
+	pending, err := s.List(ListOptions{Status: StatusPending})
+	if err != nil {
+		return nil, err
+	}
+	var series []Todo
+	for _, todo := range pending {
+		if todo.Recurrence != nil {
+			series = append(series, todo)
+		}
+	}
+	return series, nil
+}
+
+// GetOpenInstance returns the pending instance of a recurring series.
+func (s *Service) GetOpenInstance(seriesID int) (Todo, error) {
+	instance, err := s.openInstance(seriesID)
+	if err != nil {
+		return Todo{}, err
+	}
+	if instance == nil {
+		return Todo{}, fmt.Errorf("series %d has no open instance", seriesID)
+	}
+	return *instance, nil
+}
+
+// openInstance returns the pending instance of a recurring series, or nil
+// if it has none.
+func (s *Service) openInstance(seriesID int) (*Todo, error) {
+	instances, err := s.openInstances(seriesID)
+	if err != nil || len(instances) == 0 {
+		return nil, err
+	}
+	return instances[0], nil
+}
+
+// openInstances returns the pending instances of a recurring series that
+// still recur.
+func (s *Service) openInstances(seriesID int) ([]*Todo, error) {
+	pending, err := s.list(ListOptions{Status: StatusPending, Trash: WithoutTrash, SeriesID: seriesID})
+	if err != nil {
+		return nil, err
+	}
+	var instances []*Todo
+	for _, todo := range pending {
+		if todo.Recurrence != nil {
+			instances = append(instances, todo)
+		}
+	}
+	return instances, nil
+}
+
+// StopRecurrence ends the series the todo belongs to, so completing its open
+// instance no longer creates a next one.
+func (s *Service) StopRecurrence(id int) error {
+	return s.retry(func() error {
+		return s.stopRecurrence(id)
+	})
+}
+
+// stopRecurrence is StopRecurrence without retrying on conflicts.
+func (s *Service) stopRecurrence(id int) error {
+	todo, err := s.get(id)
+	if err != nil {
+		return err
+	}
+
+	if todo.SeriesID == 0 {
+		return fmt.Errorf("todo with ID %d is not recurring", id)
+	}
+
+	instances, err := s.openInstances(todo.SeriesID)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		s.markChanged(*instance)
+		instance.Recurrence = nil
+	}
+
+	if len(instances) == 0 {
+		return fmt.Errorf("series %d is already stopped", todo.SeriesID)
+	}
+
+	return s.commit(ActionStopRecurrence, id)
+}
+
+// firstOccurrence returns the first date on or after today matching the rule.
+func firstOccurrence(rule Recurrence, now time.Time) (time.Time, error) {
+	today := StartOfDay(now)
+	first, ok := rule.Next(today, today.Add(-time.Nanosecond))
+	if !ok {
+		return time.Time{}, fmt.Errorf("recurrence %s has no future occurrences", rule)
+	}
+	return first, nil
+}
+
+// nextInstance builds the instance following a completed recurring todo. It
+// is due on the first occurrence after both the previous due date and today,
+// so missed occurrences are skipped. It returns false when the series ended.
+func (s *Service) nextInstance(completed Todo, now time.Time) (Todo, bool, error) {
+	anchor := StartOfDay(completed.CreatedAt)
+	if completed.DueAt != nil {
+		anchor = *completed.DueAt
+	}
+
+	after := anchor
+	if today := StartOfDay(now); today.After(after) {
+		after = today.Add(-time.Nanosecond)
+	}
+
+	due, ok := completed.Recurrence.Next(anchor, after)
+	if !ok {
+		return Todo{}, false, nil
+	}
+
+	id, err := s.newID()
+	if err != nil {
+		return Todo{}, false, err
+	}
+
+	rule := *completed.Recurrence
+	next := Todo{
+		ID:          id,
+		Description: completed.Description,
+		Priority:    completed.Priority,
+		CreatedAt:   now,
+		DueAt:       &due,
+		Tags:        append([]string(nil), completed.Tags...),
+		Project:     completed.Project,
+		Recurrence:  &rule,
+		SeriesID:    completed.SeriesID,
+	}
+	if completed.ParentID != 0 {
+		parent, err := s.lookup(completed.ParentID)
+		if err != nil {
+			return Todo{}, false, err
+		}
+		if parent != nil && parent.DeletedAt == nil {
+			next.ParentID = completed.ParentID
+		}
+	}
+
+	return next, true, nil
+}
@@ -0,0 +1,133 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestService_Complete_Recurring(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	rule, err := ParseRecurrence("daily")
+	if err != nil {
+		t.Fatalf("Failed to parse rule: %v", err)
+	}
+
+	today := StartOfDay(time.Now())
+	first, err := service.Add("Stand-up notes", WithRecurrence(rule), WithPriority(PriorityHigh), WithTags("team"))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if first.SeriesID != first.ID {
+		t.Errorf("Expected series ID %d, got %d", first.ID, first.SeriesID)
+	}
+
+	if first.DueAt == nil || !first.DueAt.Equal(today) {
+		t.Errorf("Expected first instance due today, got %v", first.DueAt)
+	}
+
+	if err := service.Complete(first.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	next, err := service.GetOpenInstance(first.SeriesID)
+	if err != nil {
+		t.Fatalf("Expected a next instance: %v", err)
+	}
+
+	if next.ID == first.ID {
+		t.Error("Expected the next instance to get a new ID")
+	}
+	if !next.DueAt.Equal(today.AddDate(0, 0, 1)) {
+		t.Errorf("Expected next instance due tomorrow, got %v", next.DueAt)
+	}
+	if next.Description != first.Description || next.Priority != PriorityHigh || !next.HasTag("team") {
+		t.Errorf("Expected next instance to copy fields, got %+v", next)
+	}
+	if next.SeriesID != first.SeriesID {
+		t.Errorf("Expected series ID %d, got %d", first.SeriesID, next.SeriesID)
+	}
+
+	if series, err := service.GetRecurringSeries(); err != nil || len(series) != 1 {
+		t.Errorf("Expected 1 recurring series, got %v (%v)", series, err)
+	}
+}
+
+func TestService_Complete_RecurringReopened(t *testing.T) {
+	service := NewService(&MockRepository{})
+
+	rule, err := ParseRecurrence("daily")
+	if err != nil {
+		t.Fatalf("Failed to parse rule: %v", err)
+	}
+	first, err := service.Add("Water plants", WithRecurrence(rule))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if err := service.Complete(first.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.Incomplete(first.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.Complete(first.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(listTodos(t, service)) != 2 {
+		t.Errorf("Expected the first and one next instance, got %+v", listTodos(t, service))
+	}
+	if series, err := service.GetRecurringSeries(); err != nil || len(series) != 1 {
+		t.Errorf("Expected 1 open instance, got %v (%v)", series, err)
+	}
+}
+
+func TestService_StopRecurrence(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	rule, err := ParseRecurrence("weekly")
+	if err != nil {
+		t.Fatalf("Failed to parse rule: %v", err)
+	}
+
+	first, err := service.Add("Report", WithRecurrence(rule))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if err := service.Complete(first.ID); err != nil {
+		t.Fatalf("Failed to complete todo: %v", err)
+	}
+
+	next, err := service.GetOpenInstance(first.SeriesID)
+	if err != nil {
+		t.Fatalf("Expected a next instance: %v", err)
+	}
+	nextID := next.ID
+
+	// Stopping works from any instance of the series.
+	if err := service.StopRecurrence(first.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := service.StopRecurrence(first.ID); err == nil {
+		t.Error("Expected error when stopping a stopped series")
+	}
+
+	if err := service.Complete(nextID); err != nil {
+		t.Fatalf("Failed to complete todo: %v", err)
+	}
+
+	if len(listTodos(t, service)) != 2 {
+		t.Errorf("Expected no new instance after stopping, got %d todos", len(listTodos(t, service)))
+	}
+
+	plain, _ := service.Add("Plain")
+	if err := service.StopRecurrence(plain.ID); err == nil {
+		t.Error("Expected error for a non-recurring todo")
+	}
+}
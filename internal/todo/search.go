@@ -0,0 +1,182 @@
+package todo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Searchable fields reported in a Match.
+const (
+	FieldDescription = "description"
+	FieldNote        = "note"
+	FieldTag         = "tag"
+)
+
+// SearchOptions configures how Search interprets a query.
+type SearchOptions struct {
+	// Regex treats the query as a single regular expression instead of
+	// whitespace-separated terms and quoted phrases.
+	Regex bool
+}
+
+// Match describes where a query matched in one field of a todo.
+type Match struct {
+	Field string
+	// Index identifies the note or tag the match is in.
+	Index int
+	Text  string
+	// Ranges holds the byte offsets [start, end) of the matches in Text,
+	// sorted and non-overlapping.
+	Ranges [][2]int
+}
+
+// SearchResult is a todo matching a search query.
+type SearchResult struct {
+	Todo    Todo
+	Matches []Match
+}
+
+// Search returns the todos matching a case-insensitive query in their
+// description, notes or tags. Every term of the query must match somewhere
+// in the todo; terms are separated by whitespace and quoted phrases
+// ("like this") are matched as a whole.
+func (s *Service) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	patterns, err := compileSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	todos, err := s.List(ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, todo := range todos {
+		if matches, ok := matchTodo(todo, patterns); ok {
+			results = append(results, SearchResult{Todo: todo, Matches: matches})
+		}
+	}
+	return results, nil
+}
+
+func compileSearch(query string, opts SearchOptions) ([]*regexp.Regexp, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	if opts.Regex {
+		pattern, err := regexp.Compile("(?i)" + query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return []*regexp.Regexp{pattern}, nil
+	}
+
+	terms, err := splitSearchTerms(query)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]*regexp.Regexp, len(terms))
+	for i, term := range terms {
+		patterns[i] = regexp.MustCompile("(?i)" + regexp.QuoteMeta(term))
+	}
+	return patterns, nil
+}
+
+// splitSearchTerms splits a query on whitespace, keeping double-quoted
+// phrases together.
+func splitSearchTerms(query string) ([]string, error) {
+	var terms []string
+	var current strings.Builder
+	quoted := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			terms = append(terms, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			flush()
+			quoted = !quoted
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in search query")
+	}
+	flush()
+
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+	return terms, nil
+}
+
+// matchTodo reports whether every pattern matches a field of the todo and
+// returns the matching fields.
+func matchTodo(todo Todo, patterns []*regexp.Regexp) ([]Match, bool) {
+	fields := []Match{{Field: FieldDescription, Text: todo.Description}}
+	for i, note := range todo.Notes {
+		fields = append(fields, Match{Field: FieldNote, Index: i, Text: note.Text})
+	}
+	for i, tag := range todo.Tags {
+		fields = append(fields, Match{Field: FieldTag, Index: i, Text: tag})
+	}
+
+	for _, pattern := range patterns {
+		found := false
+		for i := range fields {
+			for _, loc := range pattern.FindAllStringIndex(fields[i].Text, -1) {
+				if loc[0] == loc[1] {
+					continue
+				}
+				fields[i].Ranges = append(fields[i].Ranges, [2]int{loc[0], loc[1]})
+				found = true
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	var matches []Match
+	for _, field := range fields {
+		if len(field.Ranges) > 0 {
+			field.Ranges = mergeRanges(field.Ranges)
+			matches = append(matches, field)
+		}
+	}
+	return matches, true
+}
+
+// mergeRanges sorts ranges and joins the overlapping ones.
+func mergeRanges(ranges [][2]int) [][2]int {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+
+	merged := [][2]int{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1] {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
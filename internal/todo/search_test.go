@@ -0,0 +1,130 @@
+package todo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSearchTerms(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "single term", query: "release", expected: []string{"release"}},
+		{name: "multiple terms", query: "release  notes", expected: []string{"release", "notes"}},
+		{name: "quoted phrase", query: `"release notes" draft`, expected: []string{"release notes", "draft"}},
+		{name: "unterminated quote", query: `"release notes`, wantErr: true},
+		{name: "only whitespace", query: `  ""  `, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms, err := splitSearchTerms(tt.query)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(terms, tt.expected) {
+				t.Errorf("Expected terms %v, got %v", tt.expected, terms)
+			}
+		})
+	}
+}
+
+func TestService_Search(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	first, _ := service.Add("Draft Release notes", WithTags("docs"))
+	second, _ := service.Add("Release v2")
+	_, _ = service.Add("Buy groceries")
+
+	if _, err := service.AddNote(second.ID, "Notes are pending review"); err != nil {
+		t.Fatalf("Failed to add note: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		opts     SearchOptions
+		expected []int
+		wantErr  bool
+	}{
+		{name: "case-insensitive term", query: "release", expected: []int{first.ID, second.ID}},
+		{name: "terms across fields", query: "release notes", expected: []int{first.ID, second.ID}},
+		{name: "phrase", query: `"release notes"`, expected: []int{first.ID}},
+		{name: "tag", query: "docs", expected: []int{first.ID}},
+		{name: "note", query: "review", expected: []int{second.ID}},
+		{name: "regex", query: `v\d+$`, opts: SearchOptions{Regex: true}, expected: []int{second.ID}},
+		{name: "no match", query: "deploy", expected: nil},
+		{name: "invalid regex", query: "(", opts: SearchOptions{Regex: true}, wantErr: true},
+		{name: "empty query", query: " ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := service.Search(tt.query, tt.opts)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			var ids []int
+			for _, result := range results {
+				ids = append(ids, result.Todo.ID)
+			}
+
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected IDs %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestService_Search_Ranges(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	if _, err := service.Add("release the release"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	results, err := service.Search("release rel", SearchOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(results) != 1 || len(results[0].Matches) != 1 {
+		t.Fatalf("Expected a single description match, got %v", results)
+	}
+
+	match := results[0].Matches[0]
+	if match.Field != FieldDescription {
+		t.Errorf("Expected field %s, got %s", FieldDescription, match.Field)
+	}
+
+	// Overlapping matches of both terms are merged.
+	expected := [][2]int{{0, 7}, {12, 19}}
+	if !reflect.DeepEqual(match.Ranges, expected) {
+		t.Errorf("Expected ranges %v, got %v", expected, match.Ranges)
+	}
+}
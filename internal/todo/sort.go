@@ -0,0 +1,174 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Comparator orders two todos, returning a negative number when a sorts
+// before b, a positive number when it sorts after and zero when they are
+// equal.
+type Comparator func(a, b Todo) int
+
+// sortKey is a field todos can be sorted on. Missing reports todos without
+// a value, which sort last in either direction.
+type sortKey struct {
+	compare Comparator
+	missing func(Todo) bool
+}
+
+var sortKeys = map[string]sortKey{
+	"id":          {compare: ByID},
+	"created":     {compare: ByCreated},
+	"completed":   {compare: ByCompleted, missing: func(t Todo) bool { return t.CompletedAt == nil }},
+	"due":         {compare: ByDue, missing: func(t Todo) bool { return t.DueAt == nil }},
+	"priority":    {compare: ByPriority},
+	"description": {compare: ByDescription},
+}
+
+// ByID orders todos by ID.
+func ByID(a, b Todo) int {
+	return a.ID - b.ID
+}
+
+// ByCreated orders todos by creation time.
+func ByCreated(a, b Todo) int {
+	return a.CreatedAt.Compare(b.CreatedAt)
+}
+
+// ByCompleted orders todos by completion time; pending todos sort last.
+func ByCompleted(a, b Todo) int {
+	return compareOptionalTimes(a.CompletedAt, b.CompletedAt)
+}
+
+// ByDue orders todos by due date; todos without one sort last.
+func ByDue(a, b Todo) int {
+	return compareOptionalTimes(a.DueAt, b.DueAt)
+}
+
+// ByPriority orders todos from lowest to highest priority.
+func ByPriority(a, b Todo) int {
+	return int(a.Priority) - int(b.Priority)
+}
+
+// ByDescription orders todos alphabetically, ignoring case.
+func ByDescription(a, b Todo) int {
+	return strings.Compare(strings.ToLower(a.Description), strings.ToLower(b.Description))
+}
+
+// Descending reverses a comparator.
+func Descending(cmp Comparator) Comparator {
+	return func(a, b Todo) int {
+		return cmp(b, a)
+	}
+}
+
+// Chain combines comparators, using each next one to break ties.
+func Chain(cmps ...Comparator) Comparator {
+	return func(a, b Todo) int {
+		for _, cmp := range cmps {
+			if result := cmp(a, b); result != 0 {
+				return result
+			}
+		}
+		return 0
+	}
+}
+
+// SortKey orders todos on a field: id, created, completed, due, priority or
+// description.
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// ParseSortKeys reads a comma-separated list of fields, each optionally
+// prefixed with "-" for descending order.
+func ParseSortKeys(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		key := SortKey{Field: strings.TrimPrefix(field, "-"), Descending: strings.HasPrefix(field, "-")}
+		if _, ok := sortKeys[key.Field]; !ok {
+			return nil, invalidSortField(key.Field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ParseSort builds a comparator from a comma-separated list of fields
+// (id, created, completed, due, priority, description), each optionally
+// prefixed with "-" for descending order.
+func ParseSort(spec string) (Comparator, error) {
+	keys, err := ParseSortKeys(spec)
+	if err != nil {
+		return nil, err
+	}
+	return SortBy(keys)
+}
+
+// SortBy builds a comparator ordering todos on each key in turn.
+func SortBy(keys []SortKey) (Comparator, error) {
+	cmps := make([]Comparator, 0, len(keys))
+	for _, key := range keys {
+		sortKey, ok := sortKeys[key.Field]
+		if !ok {
+			return nil, invalidSortField(key.Field)
+		}
+		cmp := sortKey.compare
+		if key.Descending {
+			cmp = Descending(cmp)
+		}
+		if sortKey.missing != nil {
+			cmp = missingLast(sortKey.missing, cmp)
+		}
+		cmps = append(cmps, cmp)
+	}
+	return Chain(cmps...), nil
+}
+
+func invalidSortField(field string) error {
+	return fmt.Errorf("invalid sort field %q (expected id, created, completed, due, priority or description)", field)
+}
+
+// SortTodos returns a copy of todos ordered by the comparator, keeping the
+// original order of equal todos.
+func SortTodos(todos []Todo, cmp Comparator) []Todo {
+	sorted := make([]Todo, len(todos))
+	copy(sorted, todos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return cmp(sorted[i], sorted[j]) < 0
+	})
+	return sorted
+}
+
+func compareOptionalTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+// missingLast orders todos without a value after the others, then falls
+// back to cmp.
+func missingLast(missing func(Todo) bool, cmp Comparator) Comparator {
+	return func(a, b Todo) int {
+		switch missingA, missingB := missing(a), missing(b); {
+		case missingA && missingB:
+			return 0
+		case missingA:
+			return 1
+		case missingB:
+			return -1
+		}
+		return cmp(a, b)
+	}
+}
@@ -0,0 +1,77 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSort(t *testing.T) {
+	day := func(d int) *time.Time {
+		date := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+
+	todos := []Todo{
+		{ID: 1, Description: "banana", Priority: PriorityLow, DueAt: day(5), CreatedAt: *day(3)},
+		{ID: 2, Description: "Apple", Priority: PriorityHigh, CreatedAt: *day(1), CompletedAt: day(4)},
+		{ID: 3, Description: "cherry", Priority: PriorityHigh, DueAt: day(2), CreatedAt: *day(2)},
+		{ID: 4, Description: "apricot", Priority: PriorityLow, DueAt: day(5), CreatedAt: *day(4), CompletedAt: day(2)},
+	}
+
+	tests := []struct {
+		spec     string
+		expected []int
+	}{
+		{"id", []int{1, 2, 3, 4}},
+		{"-id", []int{4, 3, 2, 1}},
+		{"created", []int{2, 3, 1, 4}},
+		{"description", []int{2, 4, 1, 3}},
+		{"due", []int{3, 1, 4, 2}},
+		{"-due", []int{1, 4, 3, 2}},
+		{"completed", []int{4, 2, 1, 3}},
+		{"-completed", []int{2, 4, 1, 3}},
+		{"-priority,description", []int{2, 3, 4, 1}},
+		{" -priority , -id ", []int{3, 2, 4, 1}},
+		{"due,-created", []int{3, 4, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			cmp, err := ParseSort(tt.spec)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			sorted := SortTodos(todos, cmp)
+			for i, id := range tt.expected {
+				if sorted[i].ID != id {
+					t.Errorf("Position %d: expected ID %d, got %d", i, id, sorted[i].ID)
+				}
+			}
+		})
+	}
+
+	if todos[0].ID != 1 || todos[3].ID != 4 {
+		t.Error("SortTodos should not modify its input")
+	}
+}
+
+func TestParseSort_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "name", "id,", "--id"} {
+		if _, err := ParseSort(spec); err == nil {
+			t.Errorf("Expected error for %q, got nil", spec)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	a := Todo{ID: 1, Priority: PriorityHigh}
+	b := Todo{ID: 2, Priority: PriorityHigh}
+
+	if result := Chain(ByPriority)(a, b); result != 0 {
+		t.Errorf("Expected equal todos, got %d", result)
+	}
+	if result := Chain(ByPriority, Descending(ByID))(a, b); result <= 0 {
+		t.Errorf("Expected todo 1 after todo 2, got %d", result)
+	}
+}
@@ -0,0 +1,290 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrNotFound is wrapped by the errors of Store methods given a todo that
+// is not stored.
+var ErrNotFound = errors.New("todo not found")
+
+// Store defines storage that reads and writes single todos, so that a
+// change to one todo does not rewrite the others. It holds what
+// Repository.Load returns: the active todos, including the trash. Create,
+// Update and Delete return an error wrapping ErrConflict if the stored
+// todos were changed by others since they were last read or written
+// through the store.
+type Store interface {
+	Create(todo Todo) error
+	Update(todo Todo) error
+	Delete(id int) error
+	Get(id int) (Todo, error)
+	List(opts ListOptions) ([]Todo, error)
+}
+
+// BatchStore is implemented by stores that can write several changes
+// together: the writes made by fn are all saved or, if fn or saving fails,
+// none is. The service writes the changes of each mutation in one batch.
+type BatchStore interface {
+	Store
+	Batch(fn func() error) error
+}
+
+// Status selects todos by whether they are completed.
+type Status int
+
+const (
+	// StatusAny selects pending and completed todos.
+	StatusAny Status = iota
+	// StatusPending selects the todos that are not completed.
+	StatusPending
+	// StatusCompleted selects the completed todos.
+	StatusCompleted
+)
+
+// TrashFilter selects todos by whether they are in the trash.
+type TrashFilter int
+
+const (
+	// WithTrash selects the todos in the trash and the others.
+	WithTrash TrashFilter = iota
+	// WithoutTrash selects the todos outside the trash.
+	WithoutTrash
+	// OnlyTrash selects the todos in the trash.
+	OnlyTrash
+)
+
+// ListOptions selects, orders and pages the todos returned by Store.List.
+// The options describe the todos rather than test them, so that stores
+// can select and order them where they are stored, e.g. using indexes.
+// The zero value selects all todos, ordered by ID.
+type ListOptions struct {
+	Status Status
+	Trash  TrashFilter
+	// Tags selects the todos carrying every one of these tags.
+	Tags []string
+	// DueFrom and DueBefore, when set, select the todos due at or after
+	// DueFrom and before DueBefore; todos without a due date are left out.
+	DueFrom   *time.Time
+	DueBefore *time.Time
+	// ParentID, SeriesID and BlockedBy, when set, select the subtasks of
+	// that todo, the instances of that recurring series and the todos that
+	// todo blocks, and Project the todos in that project.
+	ParentID  int
+	SeriesID  int
+	BlockedBy int
+	Project   string
+	// Sort orders the todos on each key in turn, and then by ID.
+	Sort []SortKey
+	// Offset skips that many todos, and a positive Limit returns at most
+	// that many.
+	Offset int
+	Limit  int
+}
+
+// Match reports whether the options select a todo, regardless of paging.
+func (o ListOptions) Match(todo Todo) bool {
+	switch {
+	case o.Status == StatusPending && todo.Completed,
+		o.Status == StatusCompleted && !todo.Completed,
+		o.Trash == WithoutTrash && todo.DeletedAt != nil,
+		o.Trash == OnlyTrash && todo.DeletedAt == nil,
+		o.ParentID != 0 && todo.ParentID != o.ParentID,
+		o.SeriesID != 0 && todo.SeriesID != o.SeriesID,
+		o.BlockedBy != 0 && !slices.Contains(todo.BlockedBy, o.BlockedBy),
+		o.Project != "" && todo.Project != o.Project:
+		return false
+	}
+	for _, tag := range o.Tags {
+		if !todo.HasTag(tag) {
+			return false
+		}
+	}
+	if o.DueFrom != nil || o.DueBefore != nil {
+		if todo.DueAt == nil ||
+			(o.DueFrom != nil && todo.DueAt.Before(*o.DueFrom)) ||
+			(o.DueBefore != nil && !todo.DueAt.Before(*o.DueBefore)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the todos selected by the options, in their order. Stores
+// that cannot select or order todos themselves use it on all their todos.
+func (o ListOptions) Apply(todos []Todo) ([]Todo, error) {
+	sortBy, err := SortBy(o.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []Todo
+	for _, todo := range todos {
+		if o.Match(todo) {
+			selected = append(selected, todo)
+		}
+	}
+	selected = SortTodos(selected, Chain(sortBy, ByID))
+
+	return o.Page(selected), nil
+}
+
+// Page returns the todos from Offset on, at most Limit of them.
+func (o ListOptions) Page(todos []Todo) []Todo {
+	if o.Offset > 0 {
+		todos = todos[min(o.Offset, len(todos)):]
+	}
+	if o.Limit > 0 && len(todos) > o.Limit {
+		todos = todos[:o.Limit]
+	}
+	return todos
+}
+
+// repositoryStore adapts a Repository, which loads and saves all todos at
+// once, to a BatchStore.
+type repositoryStore struct {
+	repo Repository
+	// todos holds the todos as last loaded or written, once loaded is set.
+	todos  []Todo
+	loaded bool
+	// batch is set while a batch runs, and changed once it wrote a todo.
+	batch   bool
+	changed bool
+}
+
+// NewRepositoryStore returns a store on a Repository. Reads load all todos
+// and each write saves all of them, or, within a batch, once when it ends.
+func NewRepositoryStore(repo Repository) BatchStore {
+	return &repositoryStore{repo: repo}
+}
+
+// Create adds a todo.
+func (s *repositoryStore) Create(todo Todo) error {
+	return s.write(func() error {
+		if s.index(todo.ID) >= 0 {
+			return fmt.Errorf("todo with ID %d already exists", todo.ID)
+		}
+		s.todos = append(s.todos, cloneTodo(todo))
+		return nil
+	})
+}
+
+// Update replaces a stored todo.
+func (s *repositoryStore) Update(todo Todo) error {
+	return s.write(func() error {
+		i := s.index(todo.ID)
+		if i < 0 {
+			return fmt.Errorf("%w: #%d", ErrNotFound, todo.ID)
+		}
+		s.todos[i] = cloneTodo(todo)
+		return nil
+	})
+}
+
+// Delete removes a stored todo.
+func (s *repositoryStore) Delete(id int) error {
+	return s.write(func() error {
+		i := s.index(id)
+		if i < 0 {
+			return fmt.Errorf("%w: #%d", ErrNotFound, id)
+		}
+		s.todos = slices.Delete(s.todos, i, i+1)
+		return nil
+	})
+}
+
+// Get returns a stored todo.
+func (s *repositoryStore) Get(id int) (Todo, error) {
+	if err := s.read(); err != nil {
+		return Todo{}, err
+	}
+	i := s.index(id)
+	if i < 0 {
+		return Todo{}, fmt.Errorf("%w: #%d", ErrNotFound, id)
+	}
+	return cloneTodo(s.todos[i]), nil
+}
+
+// List returns the stored todos selected by opts.
+func (s *repositoryStore) List(opts ListOptions) ([]Todo, error) {
+	if err := s.read(); err != nil {
+		return nil, err
+	}
+	todos, err := opts.Apply(s.todos)
+	if err != nil {
+		return nil, err
+	}
+	return cloneTodos(todos), nil
+}
+
+// Batch runs fn and saves the todos it wrote once it succeeds. If fn or
+// saving fails, the writes are discarded, and later writes fail the same
+// way, e.g. with ErrConflict, until the todos are read again.
+func (s *repositoryStore) Batch(fn func() error) error {
+	if s.batch {
+		return fn()
+	}
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	snapshot := cloneTodos(s.todos)
+	s.batch, s.changed = true, false
+	err := fn()
+	s.batch = false
+
+	if err == nil && s.changed {
+		err = s.repo.Save(s.todos)
+	}
+	if err != nil {
+		s.todos = snapshot
+		return err
+	}
+	return nil
+}
+
+// read loads the todos, so that reads see changes made by others, unless a
+// batch holds writes that are not saved yet.
+func (s *repositoryStore) read() error {
+	if s.batch {
+		return nil
+	}
+	s.loaded = false
+	return s.ensureLoaded()
+}
+
+func (s *repositoryStore) ensureLoaded() error {
+	if s.loaded {
+		return nil
+	}
+	todos, err := s.repo.Load()
+	if err != nil {
+		return err
+	}
+	s.todos, s.loaded = todos, true
+	return nil
+}
+
+// write applies a change to the todos and saves them, unless a batch saves
+// them when it ends.
+func (s *repositoryStore) write(apply func() error) error {
+	if s.batch {
+		if err := apply(); err != nil {
+			return err
+		}
+		s.changed = true
+		return nil
+	}
+	return s.Batch(func() error {
+		return s.write(apply)
+	})
+}
+
+func (s *repositoryStore) index(id int) int {
+	return slices.IndexFunc(s.todos, func(todo Todo) bool {
+		return todo.ID == id
+	})
+}
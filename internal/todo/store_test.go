@@ -0,0 +1,287 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// MockStore is a Store that records the writes and counts the lists made to
+// it.
+type MockStore struct {
+	todos  map[int]Todo
+	writes []string
+	lists  int
+}
+
+func (m *MockStore) Create(todo Todo) error {
+	m.writes = append(m.writes, fmt.Sprintf("create %d", todo.ID))
+	m.todos[todo.ID] = cloneTodo(todo)
+	return nil
+}
+
+func (m *MockStore) Update(todo Todo) error {
+	if _, ok := m.todos[todo.ID]; !ok {
+		return ErrNotFound
+	}
+	m.writes = append(m.writes, fmt.Sprintf("update %d", todo.ID))
+	m.todos[todo.ID] = cloneTodo(todo)
+	return nil
+}
+
+func (m *MockStore) Delete(id int) error {
+	if _, ok := m.todos[id]; !ok {
+		return ErrNotFound
+	}
+	m.writes = append(m.writes, fmt.Sprintf("delete %d", id))
+	delete(m.todos, id)
+	return nil
+}
+
+func (m *MockStore) Get(id int) (Todo, error) {
+	todo, ok := m.todos[id]
+	if !ok {
+		return Todo{}, ErrNotFound
+	}
+	return cloneTodo(todo), nil
+}
+
+func (m *MockStore) List(opts ListOptions) ([]Todo, error) {
+	m.lists++
+	var todos []Todo
+	for _, todo := range m.todos {
+		todos = append(todos, cloneTodo(todo))
+	}
+	return opts.Apply(todos)
+}
+
+func TestListOptions_Apply(t *testing.T) {
+	day := func(d int) *time.Time {
+		date := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+
+	todos := []Todo{
+		{ID: 3, Description: "c", Priority: PriorityLow, DueAt: day(3), Tags: []string{"work"}, ParentID: 1, BlockedBy: []int{2}},
+		{ID: 1, Description: "a", Priority: PriorityHigh, Completed: true, DueAt: day(1)},
+		{ID: 5, Description: "e", DeletedAt: day(1), Tags: []string{"work"}},
+		{ID: 4, Description: "d", Priority: PriorityMedium, DueAt: day(2), Tags: []string{"work", "home"}, ParentID: 1, SeriesID: 4, Project: "home"},
+		{ID: 2, Description: "b", Priority: PriorityHigh, Tags: []string{"home"}},
+	}
+
+	tests := []struct {
+		name     string
+		opts     ListOptions
+		expected []int
+	}{
+		{"defaults to ID order", ListOptions{}, []int{1, 2, 3, 4, 5}},
+		{"pending", ListOptions{Status: StatusPending}, []int{2, 3, 4, 5}},
+		{"completed", ListOptions{Status: StatusCompleted}, []int{1}},
+		{"without trash", ListOptions{Trash: WithoutTrash}, []int{1, 2, 3, 4}},
+		{"only trash", ListOptions{Trash: OnlyTrash}, []int{5}},
+		{"tags", ListOptions{Tags: []string{"WORK", "+home"}}, []int{4}},
+		{"due from", ListOptions{DueFrom: day(2)}, []int{3, 4}},
+		{"due before", ListOptions{DueBefore: day(2)}, []int{1}},
+		{"due range", ListOptions{DueFrom: day(2), DueBefore: day(3)}, []int{4}},
+		{"parent", ListOptions{ParentID: 1}, []int{3, 4}},
+		{"series", ListOptions{SeriesID: 4}, []int{4}},
+		{"blocked by", ListOptions{BlockedBy: 2}, []int{3}},
+		{"project", ListOptions{Project: "home"}, []int{4}},
+		{"sort", ListOptions{Sort: []SortKey{{Field: "priority", Descending: true}}}, []int{1, 2, 4, 3, 5}},
+		{"sort then by ID", ListOptions{Sort: []SortKey{{Field: "due"}}}, []int{1, 4, 3, 2, 5}},
+		{"page", ListOptions{Offset: 1, Limit: 2}, []int{2, 3}},
+		{"select, sort and page", ListOptions{Status: StatusPending, Tags: []string{"work"}, Sort: []SortKey{{Field: "priority"}}, Limit: 2}, []int{5, 3}},
+		{"offset past the end", ListOptions{Offset: 10}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.opts.Apply(todos)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d todos, got %d", len(tt.expected), len(result))
+			}
+			for i, id := range tt.expected {
+				if result[i].ID != id {
+					t.Errorf("Position %d: expected todo %d, got %d", i, id, result[i].ID)
+				}
+			}
+		})
+	}
+
+	if _, err := (ListOptions{Sort: []SortKey{{Field: "size"}}}).Apply(todos); err == nil {
+		t.Error("Expected error for an invalid sort field, got nil")
+	}
+}
+
+func TestRepositoryStore(t *testing.T) {
+	repo := &MockCountingRepository{}
+	store := NewRepositoryStore(repo)
+
+	for _, todo := range []Todo{{ID: 1, Description: "First"}, {ID: 2, Description: "Second"}} {
+		if err := store.Create(todo); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := store.Create(Todo{ID: 1}); err == nil {
+		t.Error("Expected error creating an existing todo, got nil")
+	}
+	if repo.saves != 2 {
+		t.Errorf("Expected 2 saves, got %d", repo.saves)
+	}
+
+	if err := store.Update(Todo{ID: 3}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Get(3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// A batch saves once, or not at all if it fails.
+	err := store.Batch(func() error {
+		if err := store.Update(Todo{ID: 1, Description: "Changed"}); err != nil {
+			return err
+		}
+		return store.Delete(2)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = store.Batch(func() error {
+		if err := store.Delete(1); err != nil {
+			return err
+		}
+		return store.Delete(2)
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if repo.saves != 3 {
+		t.Errorf("Expected 3 saves, got %d", repo.saves)
+	}
+
+	todos, err := store.List(ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(todos) != 1 || todos[0].Description != "Changed" {
+		t.Errorf("Expected only the changed todo, got %+v", todos)
+	}
+}
+
+func TestService_StoreWritesChangedTodos(t *testing.T) {
+	store := &MockStore{todos: make(map[int]Todo)}
+	service := NewStoreService(store)
+
+	for _, description := range []string{"First", "Second", "Third"} {
+		if _, err := service.Add(description); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+	if err := service.Complete(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.Delete(3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.PurgeTrash(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"create 1", "create 2", "create 3", "update 2", "update 3", "delete 3"}
+	if fmt.Sprint(store.writes) != fmt.Sprint(expected) {
+		t.Errorf("Expected writes %v, got %v", expected, store.writes)
+	}
+
+	reloaded := NewStoreService(store)
+	if todos := listTodos(t, reloaded); len(todos) != 2 || !todos[1].Completed {
+		t.Errorf("Expected todos 1 and 2 with 2 completed, got %+v", todos)
+	}
+}
+
+func TestNewStoreService_ReadsNoTodos(t *testing.T) {
+	store := &MockStore{todos: map[int]Todo{1: {ID: 1, Description: "First"}}}
+	service := NewStoreService(store)
+	if store.lists != 0 {
+		t.Errorf("Expected no todos to be listed at startup, got %d lists", store.lists)
+	}
+
+	if _, err := service.Get(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if store.lists != 0 {
+		t.Errorf("Expected a todo to be read without listing, got %d lists", store.lists)
+	}
+}
+
+func TestService_ListAndGet(t *testing.T) {
+	store := &MockStore{todos: make(map[int]Todo)}
+	service := NewStoreService(store)
+
+	for _, description := range []string{"First +work", "Second +work", "Third"} {
+		description, tags := ExtractTags(description)
+		if _, err := service.Add(description, WithTags(tags...)); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+	if err := service.Delete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	todos, err := service.List(ListOptions{Tags: []string{"work"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(todos) != 1 || todos[0].ID != 2 {
+		t.Errorf("Expected only todo 2, got %+v", todos)
+	}
+
+	if todo, err := service.Get(3); err != nil || todo.Description != "Third" {
+		t.Errorf("Expected todo 3, got %+v (%v)", todo, err)
+	}
+	for _, id := range []int{1, 9} {
+		if _, err := service.Get(id); err == nil {
+			t.Errorf("Expected error getting todo %d, got nil", id)
+		}
+	}
+}
+
+func TestService_StoreWritesOnlyChangedTodos(t *testing.T) {
+	store := &MockStore{todos: make(map[int]Todo)}
+	service := NewStoreService(store)
+
+	for _, description := range []string{"Parent", "Child", "Other"} {
+		if _, err := service.Add(description); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+	parentID := 1
+	if _, err := service.Update(2, Update{ParentID: &parentID}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store.writes = nil
+
+	// A bulk change that fails writes nothing, and leaves nothing for the
+	// next save to write.
+	if err := service.DeleteAll([]int{1, 999}); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if err := service.Complete(3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Deleting the parent changes its child, but not the other todo.
+	if err := service.Delete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"update 3", "update 1", "update 2"}
+	if fmt.Sprint(store.writes) != fmt.Sprint(expected) {
+		t.Errorf("Expected writes %v, got %v", expected, store.writes)
+	}
+	if child := store.todos[2]; child.ParentID != 0 {
+		t.Errorf("Expected todo 2 to become top-level, got parent %d", child.ParentID)
+	}
+}
@@ -0,0 +1,109 @@
+package todo
+
+import (
+	"sort"
+	"strings"
+)
+
+// TagCount holds the number of open and completed todos carrying a tag.
+type TagCount struct {
+	Tag       string
+	Open      int
+	Completed int
+}
+
+// NormalizeTag lowercases a tag and strips a leading "+".
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "+"))
+}
+
+// ExtractTags removes "+tag" tokens from a description and returns the
+// remaining text together with the normalized tags.
+func ExtractTags(description string) (string, []string) {
+	var words, tags []string
+	for _, word := range strings.Fields(description) {
+		if len(word) > 1 && strings.HasPrefix(word, "+") {
+			tags = append(tags, NormalizeTag(word))
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), tags
+}
+
+// WithTags sets the tags of a new todo.
+func WithTags(tags ...string) AddOption {
+	return func(t *Todo) {
+		for _, tag := range tags {
+			t.AddTag(tag)
+		}
+	}
+}
+
+// AddTag attaches a tag to the todo unless it is empty or already present.
+func (t *Todo) AddTag(tag string) {
+	tag = NormalizeTag(tag)
+	if tag == "" || t.HasTag(tag) {
+		return
+	}
+	t.Tags = append(t.Tags, tag)
+}
+
+// HasTag reports whether the todo carries the given tag.
+func (t Todo) HasTag(tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, existing := range t.Tags {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByAnyTag returns the todos carrying at least one of the given tags.
+func FilterByAnyTag(todos []Todo, tags []string) []Todo {
+	var filtered []Todo
+	for _, todo := range todos {
+		for _, tag := range tags {
+			if todo.HasTag(tag) {
+				filtered = append(filtered, todo)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// GetTagCounts returns every tag in use with its open and completed counts,
+// sorted by tag name.
+func (s *Service) GetTagCounts() ([]TagCount, error) {
+	todos, err := s.List(ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]*TagCount)
+	for _, todo := range todos {
+		for _, tag := range todo.Tags {
+			count, ok := counts[tag]
+			if !ok {
+				count = &TagCount{Tag: tag}
+				counts[tag] = count
+			}
+			if todo.Completed {
+				count.Completed++
+			} else {
+				count.Open++
+			}
+		}
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, *count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tag < result[j].Tag
+	})
+	return result, nil
+}
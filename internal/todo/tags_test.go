@@ -0,0 +1,97 @@
+package todo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedDesc string
+		expectedTags []string
+	}{
+		{name: "no tags", input: "Buy groceries", expectedDesc: "Buy groceries"},
+		{name: "trailing tags", input: "Deploy api +Backend +infra", expectedDesc: "Deploy api", expectedTags: []string{"backend", "infra"}},
+		{name: "inline tag", input: "Write +docs for release", expectedDesc: "Write for release", expectedTags: []string{"docs"}},
+		{name: "lone plus", input: "1 + 1", expectedDesc: "1 + 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc, tags := ExtractTags(tt.input)
+
+			if desc != tt.expectedDesc {
+				t.Errorf("Expected description %q, got %q", tt.expectedDesc, desc)
+			}
+
+			if !reflect.DeepEqual(tags, tt.expectedTags) {
+				t.Errorf("Expected tags %v, got %v", tt.expectedTags, tags)
+			}
+		})
+	}
+}
+
+func TestTodo_AddTag(t *testing.T) {
+	todoItem := Todo{}
+	todoItem.AddTag("Infra")
+	todoItem.AddTag("+infra")
+	todoItem.AddTag("")
+	todoItem.AddTag("docs")
+
+	expected := []string{"infra", "docs"}
+	if !reflect.DeepEqual(todoItem.Tags, expected) {
+		t.Errorf("Expected tags %v, got %v", expected, todoItem.Tags)
+	}
+
+	if !todoItem.HasTag("INFRA") {
+		t.Error("Expected todo to have tag infra")
+	}
+}
+
+func TestFilterByAnyTag(t *testing.T) {
+	todos := []Todo{
+		{ID: 1, Tags: []string{"backend", "infra"}},
+		{ID: 2, Tags: []string{"backend"}},
+		{ID: 3, Tags: []string{"docs"}},
+		{ID: 4},
+	}
+
+	anyTag := FilterByAnyTag(todos, []string{"infra", "docs"})
+	if len(anyTag) != 2 || anyTag[0].ID != 1 || anyTag[1].ID != 3 {
+		t.Errorf("Expected todos 1 and 3 to have any tag, got %v", anyTag)
+	}
+}
+
+func TestService_GetTagCounts(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	first, err := service.Add("First", WithTags("infra", "backend"))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	_, err = service.Add("Second", WithTags("infra"))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if err := service.Complete(first.ID); err != nil {
+		t.Fatalf("Failed to complete todo: %v", err)
+	}
+
+	counts, err := service.GetTagCounts()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []TagCount{
+		{Tag: "backend", Open: 0, Completed: 1},
+		{Tag: "infra", Open: 1, Completed: 1},
+	}
+
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected counts %v, got %v", expected, counts)
+	}
+}
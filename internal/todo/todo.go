@@ -1,17 +1,32 @@
 package todo
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"time"
 )
 
 // Todo represents a single todo item.
 type Todo struct {
-	ID          int        `json:"id"`
-	Description string     `json:"description"`
-	Completed   bool       `json:"completed"`
-	CreatedAt   time.Time  `json:"created_at"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ID          int         `json:"id"`
+	Description string      `json:"description"`
+	Completed   bool        `json:"completed"`
+	Priority    Priority    `json:"priority,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+	DueAt       *time.Time  `json:"due_at,omitempty"`
+	Tags        []string    `json:"tags,omitempty"`
+	Project     string      `json:"project,omitempty"`
+	UpdatedAt   *time.Time  `json:"updated_at,omitempty"`
+	ParentID    int         `json:"parent_id,omitempty"`
+	BlockedBy   []int       `json:"blocked_by,omitempty"`
+	Recurrence  *Recurrence `json:"recurrence,omitempty"`
+	SeriesID    int         `json:"series_id,omitempty"`
+	Notes       []Note      `json:"notes,omitempty"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty"`
 }
 
 // Stats represents todo statistics.
@@ -19,6 +34,7 @@ type Stats struct {
 	Total     int
 	Completed int
 	Pending   int
+	Overdue   int
 }
 
 // CompletionRate calculates the completion rate as a percentage.
@@ -29,7 +45,11 @@ func (s Stats) CompletionRate() float64 {
 	return float64(s.Completed) / float64(s.Total) * 100
 }
 
-// Repository defines the interface for todo storage operations.
+// Repository defines storage that loads and saves all todos at once. Save
+// returns an error wrapping ErrConflict, rather than overwriting, if the
+// stored todos were changed since they were last loaded or saved through
+// the repository. Repositories that can also read and write single todos
+// implement Store, which the service then uses instead.
 type Repository interface {
 	Save(todos []Todo) error
 	Load() ([]Todo, error)
@@ -37,79 +57,311 @@ type Repository interface {
 
 // Service handles business logic for todo operations.
 type Service struct {
-	repo   Repository
-	todos  []Todo
+	// store holds the todos, and repo is the repository or store the
+	// service was created on, which may also implement ProjectRepository,
+	// JournalRepository, ArchiveRepository, HistoryRepository and
+	// IDRepository.
+	store Store
+	repo  any
+	// todos holds the todos the running mutation read, created or
+	// changed, including the trash, by ID, and nil for the ones it removed
+	// or did not find. It is nil outside mutations, which read the todos
+	// from the store.
+	todos    map[int]*Todo
+	projects []Project
+	// nextID is the ID of the next new todo, or 0 before a mutation first
+	// needs one, and lastID the highest ID given, as stored by an
+	// IDRepository or else kept by the service.
 	nextID int
+	lastID int
+	// changed holds the todos changed since the last save as they were
+	// then, or nil for the ones created since, so that a save only writes
+	// those and records them in the journal, and writes counts the saves,
+	// so that a mutation is not run again once it wrote anything.
+	changed map[int]*Todo
+	writes  int
+	journal Journal
+	// lockTimeout is how long mutations wait for the store's lock, and
+	// holdsLock is set while they hold it.
+	lockTimeout time.Duration
+	holdsLock   bool
+	// archived holds the archive once it has been read.
+	archived      []Todo
+	archiveLoaded bool
+	// actor is who makes changes, and history holds the changes made when
+	// the repository cannot persist them.
+	actor   string
+	history []Change
+	// warnings receives messages about problems that do not fail a
+	// mutation, such as history that could not be recorded.
+	warnings io.Writer
 }
 
 // NewService creates a new todo service.
 func NewService(repo Repository) *Service {
+	store, ok := repo.(Store)
+	if !ok {
+		store = NewRepositoryStore(repo)
+	}
+	return newService(store, repo)
+}
+
+// NewStoreService creates a todo service on a store, which, like a
+// Repository, may also store projects, the journal, the archive and the
+// history.
+func NewStoreService(store Store) *Service {
+	return newService(store, store)
+}
+
+func newService(store Store, repo any) *Service {
 	service := &Service{
-		repo:   repo,
-		todos:  make([]Todo, 0),
-		nextID: 1,
+		store:    store,
+		repo:     repo,
+		warnings: os.Stderr,
 	}
 
-	if err := service.loadTodos(); err != nil {
+	// The todos are read from the store as they are needed, so only the
+	// projects and the journal are loaded here.
+	if err := service.loadSidecars(); err != nil {
 		// Log error but do not fail, as this might be the first run.
-		fmt.Printf("Warning: could not load existing todos: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: could not load existing projects and journal: %v\n", err)
 	}
 
 	return service
 }
 
+// AddOption configures optional fields of a todo created by Add.
+type AddOption func(*Todo)
+
+// WithPriority sets the priority of a new todo.
+func WithPriority(priority Priority) AddOption {
+	return func(t *Todo) {
+		t.Priority = priority
+	}
+}
+
+// WithDueAt sets the due date of a new todo.
+func WithDueAt(due time.Time) AddOption {
+	return func(t *Todo) {
+		t.DueAt = &due
+	}
+}
+
 // Add creates a new todo item.
-func (s *Service) Add(description string) (*Todo, error) {
+func (s *Service) Add(description string, opts ...AddOption) (*Todo, error) {
+	var added *Todo
+	err := s.retry(func() error {
+		var err error
+		added, err = s.add(description, opts...)
+		return err
+	})
+	return added, err
+}
+
+// add is Add without retrying on conflicts.
+func (s *Service) add(description string, opts ...AddOption) (*Todo, error) {
 	if description == "" {
 		return nil, fmt.Errorf("description cannot be empty")
 	}
 
+	id, err := s.newID()
+	if err != nil {
+		return nil, err
+	}
+
 	todo := Todo{
-		ID:          s.nextID,
+		ID:          id,
 		Description: description,
 		Completed:   false,
 		CreatedAt:   time.Now(),
 	}
+	for _, opt := range opts {
+		opt(&todo)
+	}
 
-	s.todos = append(s.todos, todo)
-	s.nextID++
+	if err := s.validateProject(todo.Project); err != nil {
+		return nil, err
+	}
 
-	if err := s.save(); err != nil {
+	if err := s.validateParent(0, todo.ParentID); err != nil {
+		return nil, err
+	}
+
+	if todo.Recurrence != nil {
+		todo.SeriesID = todo.ID
+		if todo.DueAt == nil {
+			first, err := firstOccurrence(*todo.Recurrence, todo.CreatedAt)
+			if err != nil {
+				return nil, err
+			}
+			todo.DueAt = &first
+		}
+	}
+
+	s.markCreated(todo)
+
+	if err := s.commit(ActionAdd, todo.ID); err != nil {
 		return nil, fmt.Errorf("failed to save todo: %w", err)
 	}
 
 	return &todo, nil
 }
 
-// GetAll returns all todos.
-func (s *Service) GetAll() []Todo {
-	return s.todos
+// List returns the todos outside the trash selected by opts, reading them
+// from the store, so that stores that select todos themselves read no
+// others.
+func (s *Service) List(opts ListOptions) ([]Todo, error) {
+	opts.Trash = WithoutTrash
+	todos, err := s.store.List(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list todos: %w", err)
+	}
+	return todos, nil
 }
 
-// GetByStatus returns todos filtered by completion status.
-func (s *Service) GetByStatus(completed bool) []Todo {
-	var filtered []Todo
-	for _, todo := range s.todos {
-		if todo.Completed == completed {
-			filtered = append(filtered, todo)
+// Get returns a todo outside the trash by its ID, reading it from the
+// store.
+func (s *Service) Get(id int) (Todo, error) {
+	todo, err := s.get(id)
+	if err != nil {
+		return Todo{}, err
+	}
+	return *todo, nil
+}
+
+// get returns the todo with the given ID outside the trash, as the running
+// mutation changed it.
+func (s *Service) get(id int) (*Todo, error) {
+	todo, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	if todo == nil || todo.DeletedAt != nil {
+		return nil, fmt.Errorf("todo with ID %d not found", id)
+	}
+	return todo, nil
+}
+
+// lookup returns the todo with the given ID, including the trash, as the
+// running mutation changed it, or nil if there is none. A mutation reads
+// each todo from the store once and then changes it in place.
+func (s *Service) lookup(id int) (*Todo, error) {
+	if todo, ok := s.todos[id]; ok {
+		return todo, nil
+	}
+
+	var found *Todo
+	todo, err := s.store.Get(id)
+	switch {
+	case err == nil:
+		found = &todo
+	case !errors.Is(err, ErrNotFound):
+		return nil, fmt.Errorf("failed to read todo: %w", err)
+	}
+	if s.todos != nil {
+		s.todos[id] = found
+	}
+	return found, nil
+}
+
+// list returns the todos selected by opts, regardless of sorting and
+// paging, as the running mutation changed them, ordered by ID.
+func (s *Service) list(opts ListOptions) ([]*Todo, error) {
+	opts.Sort, opts.Offset, opts.Limit = nil, 0, 0
+	stored, err := s.store.List(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list todos: %w", err)
+	}
+
+	var todos []*Todo
+	for i := range stored {
+		todo, ok := s.todos[stored[i].ID]
+		if !ok {
+			todo = &stored[i]
+			if s.todos != nil {
+				s.todos[todo.ID] = todo
+			}
+		}
+		if todo != nil && opts.Match(*todo) {
+			todos = append(todos, todo)
 		}
 	}
-	return filtered
+
+	// The mutation may have created todos, or changed others, that opts
+	// select now but not as they are stored.
+	listed := make(map[int]bool, len(stored))
+	for _, todo := range stored {
+		listed[todo.ID] = true
+	}
+	for id, todo := range s.todos {
+		if !listed[id] && todo != nil && opts.Match(*todo) {
+			todos = append(todos, todo)
+		}
+	}
+
+	sort.Slice(todos, func(i, j int) bool {
+		return todos[i].ID < todos[j].ID
+	})
+	return todos, nil
 }
 
-// GetByID returns a todo by its ID.
-func (s *Service) GetByID(id int) (*Todo, error) {
-	for i, todo := range s.todos {
-		if todo.ID == id {
-			return &s.todos[i], nil
+// newID returns the ID for a new todo.
+func (s *Service) newID() (int, error) {
+	if err := s.loadNextID(); err != nil {
+		return 0, err
+	}
+	id := s.nextID
+	s.nextID++
+	return id, nil
+}
+
+// loadNextID works out the ID of the next new todo, unless the mutation did
+// before: one above the highest ID given so far, counting the todos in the
+// trash, purged todos, which the stored last ID covers, and, until an ID is
+// stored, archived todos.
+func (s *Service) loadNextID() error {
+	if s.nextID != 0 {
+		return nil
+	}
+
+	next := s.lastID + 1
+	last, err := s.store.List(ListOptions{Sort: []SortKey{{Field: "id", Descending: true}}, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("failed to list todos: %w", err)
+	}
+	for _, todo := range last {
+		next = max(next, todo.ID+1)
+	}
+	if s.lastID == 0 {
+		archived, err := s.GetArchived()
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		for _, todo := range archived {
+			next = max(next, todo.ID+1)
 		}
 	}
-	return nil, fmt.Errorf("todo with ID %d not found", id)
+	for id, todo := range s.todos {
+		if todo != nil {
+			next = max(next, id+1)
+		}
+	}
+
+	s.nextID = next
+	return nil
+}
+
+// Complete marks a todo as completed. A todo with open subtasks is only
+// completed when CompleteCascade or CompleteForce is given, and a todo with
+// open blockers only when CompleteForce is given. Completing an instance of a
+// recurring series creates the next instance.
+func (s *Service) Complete(id int, opts ...CompleteOption) error {
+	return s.CompleteAll([]int{id}, opts...)
 }
 
-// Complete marks a todo as completed.
-func (s *Service) Complete(id int) error {
-	todo, err := s.GetByID(id)
+// complete marks a todo as completed without saving.
+func (s *Service) complete(id int, config completeConfig) error {
+	todo, err := s.get(id)
 	if err != nil {
 		return err
 	}
@@ -118,16 +370,62 @@ func (s *Service) Complete(id int) error {
 		return fmt.Errorf("todo with ID %d is already completed", id)
 	}
 
-	todo.Completed = true
+	blockers, err := s.OpenBlockers(*todo)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 && !config.force {
+		return fmt.Errorf("todo with ID %d is blocked by open todo(s) %s; use force", id, JoinIDs(blockers, ", "))
+	}
+
+	open, err := s.openDescendants(id)
+	if err != nil {
+		return err
+	}
+	if len(open) > 0 && !config.cascade && !config.force {
+		return fmt.Errorf("todo with ID %d has %d open subtask(s); use cascade or force", id, len(open))
+	}
+
 	now := time.Now()
+	if config.cascade {
+		if err := s.completeDescendants(id, now); err != nil {
+			return err
+		}
+	}
+
+	s.markChanged(*todo)
+	todo.Completed = true
 	todo.CompletedAt = &now
 
-	return s.save()
+	// An instance that was reopened after the next one was created does
+	// not create another when it is completed again.
+	if todo.Recurrence != nil {
+		instance, err := s.openInstance(todo.SeriesID)
+		if err != nil {
+			return err
+		}
+		if instance == nil {
+			next, ok, err := s.nextInstance(*todo, now)
+			if err != nil {
+				return err
+			}
+			if ok {
+				s.markCreated(next)
+			}
+		}
+	}
+
+	return nil
 }
 
 // Incomplete marks a todo as not completed.
 func (s *Service) Incomplete(id int) error {
-	todo, err := s.GetByID(id)
+	return s.IncompleteAll([]int{id})
+}
+
+// incomplete marks a todo as not completed without saving.
+func (s *Service) incomplete(id int) error {
+	todo, err := s.get(id)
 	if err != nil {
 		return err
 	}
@@ -136,58 +434,252 @@ func (s *Service) Incomplete(id int) error {
 		return fmt.Errorf("todo with ID %d is already incomplete", id)
 	}
 
+	s.markChanged(*todo)
 	todo.Completed = false
 	todo.CompletedAt = nil
 
-	return s.save()
+	return nil
+}
+
+// Update describes changes to the fields of a todo. Nil fields are left
+// unchanged.
+type Update struct {
+	Description *string
+	Priority    *Priority
+	DueAt       *time.Time
+	ClearDueAt  bool
+	Tags        *[]string
+	Project     *string
+	ParentID    *int
 }
 
-// Delete removes a todo by ID.
-func (s *Service) Delete(id int) error {
-	for i, todo := range s.todos {
-		if todo.ID == id {
-			s.todos = append(s.todos[:i], s.todos[i+1:]...)
-			return s.save()
+// Update applies changes to a todo and records when it was updated.
+func (s *Service) Update(id int, update Update) (*Todo, error) {
+	var updated *Todo
+	err := s.retry(func() error {
+		var err error
+		updated, err = s.update(id, update)
+		return err
+	})
+	return updated, err
+}
+
+// update is Update without retrying on conflicts.
+func (s *Service) update(id int, update Update) (*Todo, error) {
+	todo, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *todo
+	if update.Description != nil {
+		if *update.Description == "" {
+			return nil, fmt.Errorf("description cannot be empty")
+		}
+		updated.Description = *update.Description
+	}
+	if update.Priority != nil {
+		updated.Priority = *update.Priority
+	}
+	if update.ClearDueAt {
+		updated.DueAt = nil
+	} else if update.DueAt != nil {
+		due := *update.DueAt
+		updated.DueAt = &due
+	}
+	if update.Tags != nil {
+		updated.Tags = nil
+		for _, tag := range *update.Tags {
+			updated.AddTag(tag)
+		}
+	}
+	if update.Project != nil && *update.Project != todo.Project {
+		if err := s.validateProject(*update.Project); err != nil {
+			return nil, err
+		}
+		updated.Project = *update.Project
+	}
+	if update.ParentID != nil {
+		if err := s.validateParent(id, *update.ParentID); err != nil {
+			return nil, err
+		}
+		updated.ParentID = *update.ParentID
+	}
+
+	now := time.Now()
+	updated.UpdatedAt = &now
+	s.markChanged(*todo)
+	*todo = updated
+
+	if err := s.commit(ActionEdit, id); err != nil {
+		return nil, fmt.Errorf("failed to save todo: %w", err)
+	}
+
+	return todo, nil
+}
+
+// SetPriority changes the priority of a todo.
+func (s *Service) SetPriority(id int, priority Priority) error {
+	_, err := s.Update(id, Update{Priority: &priority})
+	return err
+}
+
+// Delete moves a todo to the trash. Its subtasks are moved as well with
+// DeleteCascade, and otherwise move up to the deleted todo's parent. Other
+// todos stop being blocked by the deleted ones.
+func (s *Service) Delete(id int, opts ...DeleteOption) error {
+	return s.DeleteAll([]int{id}, opts...)
+}
+
+// delete moves a todo to the trash without saving.
+func (s *Service) delete(id int, config deleteConfig) error {
+	todo, err := s.get(id)
+	if err != nil {
+		return err
+	}
+
+	removed := []*Todo{todo}
+	if config.cascade {
+		descendants, err := s.GetDescendants(id)
+		if err != nil {
+			return err
+		}
+		for _, descendant := range descendants {
+			t, err := s.get(descendant.ID)
+			if err != nil {
+				return err
+			}
+			removed = append(removed, t)
 		}
 	}
-	return fmt.Errorf("todo with ID %d not found", id)
+
+	now := time.Now()
+	removedIDs := make(map[int]bool, len(removed))
+	for _, t := range removed {
+		s.markChanged(*t)
+		t.DeletedAt = &now
+		removedIDs[t.ID] = true
+	}
+
+	children, err := s.list(ListOptions{Trash: WithoutTrash, ParentID: id})
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		s.markChanged(*child)
+		child.ParentID = todo.ParentID
+	}
+
+	for _, t := range removed {
+		blocked, err := s.list(ListOptions{Trash: WithoutTrash, BlockedBy: t.ID})
+		if err != nil {
+			return err
+		}
+		for _, b := range blocked {
+			s.markChanged(*b)
+			b.BlockedBy = withoutIDs(b.BlockedBy, removedIDs)
+		}
+	}
+
+	return nil
+}
+
+// GetStats returns statistics about the todos outside the trash.
+func (s *Service) GetStats() (Stats, error) {
+	todos, err := s.List(ListOptions{})
+	if err != nil {
+		return Stats{}, err
+	}
+	return computeStats(todos, time.Now()), nil
 }
 
-// GetStats returns statistics about todos.
-func (s *Service) GetStats() Stats {
+func computeStats(todos []Todo, now time.Time) Stats {
 	stats := Stats{
-		Total: len(s.todos),
+		Total: len(todos),
 	}
 
-	for _, todo := range s.todos {
+	for _, todo := range todos {
 		if todo.Completed {
 			stats.Completed++
 		} else {
 			stats.Pending++
 		}
+		if todo.IsOverdue(now) {
+			stats.Overdue++
+		}
 	}
 
 	return stats
 }
 
-func (s *Service) save() error {
-	return s.repo.Save(s.todos)
-}
-
-func (s *Service) loadTodos() error {
-	todos, err := s.repo.Load()
-	if err != nil {
+// save persists the todos and records the changes made by the given action
+// in the history.
+func (s *Service) save(action string) error {
+	// The last ID is stored first, so that it never falls behind the IDs
+	// of stored todos.
+	if err := s.saveLastID(); err != nil {
+		return err
+	}
+	before, after := s.changes()
+	changes := s.historyChanges(action, before, after)
+	if err := s.write(before, after); err != nil {
+		return err
+	}
+	s.writes++
+	s.changed = nil
+	if err := s.saveJournal(); err != nil {
 		return err
 	}
 
-	s.todos = todos
+	// The todos are saved by now, so a history that cannot be recorded
+	// does not fail the mutation.
+	if err := s.appendHistory(changes); err != nil {
+		_, _ = fmt.Fprintf(s.warnings, "Warning: could not record history: %v\n", err)
+	}
+	return nil
+}
+
+// write writes the todos that changed since they were last saved, given as
+// they were and as they are, to the store, in one batch if the store
+// supports it.
+func (s *Service) write(before, after []Todo) error {
+	existed := make(map[int]bool, len(before))
+	for _, todo := range before {
+		existed[todo.ID] = true
+	}
 
-	// Set nextID to the highest ID + 1.
-	for _, todo := range s.todos {
-		if todo.ID >= s.nextID {
-			s.nextID = todo.ID + 1
+	write := func() error {
+		kept := make(map[int]bool, len(after))
+		for _, todo := range after {
+			kept[todo.ID] = true
+			write := s.store.Create
+			if existed[todo.ID] {
+				write = s.store.Update
+			}
+			if err := write(todo); err != nil {
+				return err
+			}
 		}
+		for _, todo := range before {
+			if !kept[todo.ID] {
+				if err := s.store.Delete(todo.ID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
 	}
 
-	return nil
+	if batch, ok := s.store.(BatchStore); ok {
+		return batch.Batch(write)
+	}
+	return write()
+}
+
+// loadSidecars loads what the repository stores besides the todos.
+func (s *Service) loadSidecars() error {
+	if err := s.loadProjects(); err != nil {
+		return err
+	}
+	return s.loadJournal()
 }
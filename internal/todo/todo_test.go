@@ -2,6 +2,7 @@ package todo
 
 import (
 	"testing"
+	"time"
 )
 
 // MockRepository is a mock implementation of Repository for testing.
@@ -28,6 +29,26 @@ func (m *MockRepository) Load() ([]Todo, error) {
 	return result, nil
 }
 
+// listTodos returns the todos outside the trash.
+func listTodos(t *testing.T, service *Service) []Todo {
+	t.Helper()
+	todos, err := service.List(ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list todos: %v", err)
+	}
+	return todos
+}
+
+// trashTodos returns the todos in the trash.
+func trashTodos(t *testing.T, service *Service) []Todo {
+	t.Helper()
+	trash, err := service.GetTrash()
+	if err != nil {
+		t.Fatalf("Failed to list the trash: %v", err)
+	}
+	return trash
+}
+
 func TestNewService(t *testing.T) {
 	repo := &MockRepository{}
 	service := NewService(repo)
@@ -40,12 +61,8 @@ func TestNewService(t *testing.T) {
 		t.Error("Service repository not set correctly")
 	}
 
-	if service.nextID != 1 {
-		t.Errorf("Expected nextID to be 1, got %d", service.nextID)
-	}
-
-	if len(service.todos) != 0 {
-		t.Errorf("Expected empty todos slice, got %d items", len(service.todos))
+	if service.todos != nil {
+		t.Errorf("Expected no todos to be held outside mutations, got %d", len(service.todos))
 	}
 }
 
@@ -117,7 +134,7 @@ func TestService_GetByID(t *testing.T) {
 	}
 
 	// Test getting the todo by ID.
-	todoItem, err := service.GetByID(addedTodo.ID)
+	todoItem, err := service.Get(addedTodo.ID)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -127,7 +144,7 @@ func TestService_GetByID(t *testing.T) {
 	}
 
 	// Test getting non-existent todo.
-	_, err = service.GetByID(999)
+	_, err = service.Get(999)
 	if err == nil {
 		t.Error("Expected error for non-existent todo")
 	}
@@ -150,7 +167,7 @@ func TestService_Complete(t *testing.T) {
 	}
 
 	// Check if the todo is completed.
-	todoItem, err := service.GetByID(addedTodo.ID)
+	todoItem, err := service.Get(addedTodo.ID)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -192,7 +209,7 @@ func TestService_Incomplete(t *testing.T) {
 	}
 
 	// Check if the todo is incomplete.
-	todoItem, err := service.GetByID(addedTodo.ID)
+	todoItem, err := service.Get(addedTodo.ID)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -229,7 +246,7 @@ func TestService_Delete(t *testing.T) {
 	}
 
 	// Check if the todo is deleted.
-	_, err = service.GetByID(addedTodo.ID)
+	_, err = service.Get(addedTodo.ID)
 	if err == nil {
 		t.Error("Expected error for deleted todo")
 	}
@@ -246,13 +263,16 @@ func TestService_GetStats(t *testing.T) {
 	service := NewService(repo)
 
 	// Test with no todos.
-	stats := service.GetStats()
+	stats, err := service.GetStats()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	if stats.Total != 0 || stats.Completed != 0 || stats.Pending != 0 {
 		t.Error("Expected all stats to be 0 for empty service")
 	}
 
 	// Add some todos.
-	_, err := service.Add("Todo 1")
+	_, err = service.Add("Todo 1")
 	if err != nil {
 		t.Fatalf("Failed to add todo: %v", err)
 	}
@@ -274,7 +294,10 @@ func TestService_GetStats(t *testing.T) {
 	}
 
 	// Check stats.
-	stats = service.GetStats()
+	stats, err = service.GetStats()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	if stats.Total != 3 {
 		t.Errorf("Expected total 3, got %d", stats.Total)
 	}
@@ -324,7 +347,7 @@ func TestStats_CompletionRate(t *testing.T) {
 	}
 }
 
-func TestService_GetByStatus(t *testing.T) {
+func TestService_List_ByStatus(t *testing.T) {
 	repo := &MockRepository{}
 	service := NewService(repo)
 
@@ -356,13 +379,19 @@ func TestService_GetByStatus(t *testing.T) {
 	}
 
 	// Test getting completed todos.
-	completed := service.GetByStatus(true)
+	completed, err := service.List(ListOptions{Status: StatusCompleted})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	if len(completed) != 2 {
 		t.Errorf("Expected 2 completed todos, got %d", len(completed))
 	}
 
 	// Test getting pending todos.
-	pending := service.GetByStatus(false)
+	pending, err := service.List(ListOptions{Status: StatusPending})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	if len(pending) != 1 {
 		t.Errorf("Expected 1 pending todo, got %d", len(pending))
 	}
@@ -371,3 +400,141 @@ func TestService_GetByStatus(t *testing.T) {
 		t.Errorf("Expected pending todo ID %d, got %d", todo2.ID, pending[0].ID)
 	}
 }
+
+func TestService_SetPriority(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	addedTodo, err := service.Add("Test todo", WithPriority(PriorityLow))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if addedTodo.Priority != PriorityLow {
+		t.Errorf("Expected priority low, got %s", addedTodo.Priority)
+	}
+
+	err = service.SetPriority(addedTodo.ID, PriorityUrgent)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	todoItem, err := service.Get(addedTodo.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if todoItem.Priority != PriorityUrgent {
+		t.Errorf("Expected priority urgent, got %s", todoItem.Priority)
+	}
+
+	// Test setting priority of non-existent todo.
+	err = service.SetPriority(999, PriorityHigh)
+	if err == nil {
+		t.Error("Expected error for non-existent todo")
+	}
+}
+
+func TestService_GetStats_Overdue(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	yesterday := StartOfDay(time.Now()).AddDate(0, 0, -1)
+	tomorrow := StartOfDay(time.Now()).AddDate(0, 0, 1)
+
+	_, err := service.Add("Overdue todo", WithDueAt(yesterday))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	_, err = service.Add("Upcoming todo", WithDueAt(tomorrow))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	completed, err := service.Add("Completed overdue todo", WithDueAt(yesterday))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if err := service.Complete(completed.ID); err != nil {
+		t.Fatalf("Failed to complete todo: %v", err)
+	}
+
+	stats, err := service.GetStats()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats.Overdue != 1 {
+		t.Errorf("Expected overdue 1, got %d", stats.Overdue)
+	}
+}
+
+func TestService_Update(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	due := time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)
+	addedTodo, err := service.Add("Tpyo", WithDueAt(due), WithTags("old"))
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	if addedTodo.UpdatedAt != nil {
+		t.Error("UpdatedAt should be nil for a new todo")
+	}
+
+	description := "Typo"
+	priority := PriorityHigh
+	tags := []string{"new", "+Other"}
+	updated, err := service.Update(addedTodo.ID, Update{
+		Description: &description,
+		Priority:    &priority,
+		ClearDueAt:  true,
+		Tags:        &tags,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if updated.ID != addedTodo.ID || !updated.CreatedAt.Equal(addedTodo.CreatedAt) {
+		t.Error("Update should keep the ID and CreatedAt")
+	}
+	if updated.Description != "Typo" {
+		t.Errorf("Expected description Typo, got %s", updated.Description)
+	}
+	if updated.Priority != PriorityHigh {
+		t.Errorf("Expected priority high, got %s", updated.Priority)
+	}
+	if updated.DueAt != nil {
+		t.Error("DueAt should be cleared")
+	}
+	if len(updated.Tags) != 2 || updated.Tags[0] != "new" || updated.Tags[1] != "other" {
+		t.Errorf("Expected tags [new other], got %v", updated.Tags)
+	}
+	if updated.UpdatedAt == nil {
+		t.Error("UpdatedAt should be set")
+	}
+
+	// The change is persisted.
+	if repo.todos[0].Description != "Typo" {
+		t.Errorf("Expected saved description Typo, got %s", repo.todos[0].Description)
+	}
+
+	// Test an empty description.
+	empty := ""
+	if _, err := service.Update(addedTodo.ID, Update{Description: &empty}); err == nil {
+		t.Error("Expected error for empty description")
+	}
+
+	// Test a non-existent project.
+	project := "missing"
+	if _, err := service.Update(addedTodo.ID, Update{Project: &project}); err == nil {
+		t.Error("Expected error for non-existent project")
+	}
+
+	// Test updating a non-existent todo.
+	if _, err := service.Update(999, Update{Description: &description}); err == nil {
+		t.Error("Expected error for non-existent todo")
+	}
+}
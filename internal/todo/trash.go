@@ -0,0 +1,174 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// IDRepository is implemented by repositories that keep the highest ID
+// given to a todo, so that the IDs of purged todos are not given again.
+// LoadLastID returns 0 if no ID was stored yet.
+type IDRepository interface {
+	SaveLastID(id int) error
+	LoadLastID() (int, error)
+}
+
+// GetTrash returns the deleted todos, most recently deleted first.
+func (s *Service) GetTrash() ([]Todo, error) {
+	trash, err := s.store.List(ListOptions{Trash: OnlyTrash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list todos: %w", err)
+	}
+	sort.SliceStable(trash, func(i, j int) bool {
+		if !trash[i].DeletedAt.Equal(*trash[j].DeletedAt) {
+			return trash[i].DeletedAt.After(*trash[j].DeletedAt)
+		}
+		return trash[i].ID < trash[j].ID
+	})
+	return trash, nil
+}
+
+// Restore moves a todo out of the trash, together with the subtasks that
+// were deleted with it. A restored todo whose parent is no longer available
+// becomes a top-level todo.
+func (s *Service) Restore(id int) ([]Todo, error) {
+	var restored []Todo
+	err := s.retry(func() error {
+		var err error
+		restored, err = s.restore(id)
+		return err
+	})
+	return restored, err
+}
+
+// restore is Restore without retrying on conflicts.
+func (s *Service) restore(id int) ([]Todo, error) {
+	deleted, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	if deleted == nil || deleted.DeletedAt == nil {
+		return nil, fmt.Errorf("todo with ID %d is not in the trash", id)
+	}
+
+	// Subtasks deleted by the same cascade share the deletion time.
+	restore := []*Todo{deleted}
+	deletedAt := *deleted.DeletedAt
+	for i := 0; i < len(restore); i++ {
+		children, err := s.list(ListOptions{Trash: OnlyTrash, ParentID: restore[i].ID})
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if child.DeletedAt.Equal(deletedAt) {
+				restore = append(restore, child)
+			}
+		}
+	}
+
+	if deleted.ParentID != 0 {
+		parent, err := s.lookup(deleted.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil || parent.DeletedAt != nil {
+			s.markChanged(*deleted)
+			deleted.ParentID = 0
+		}
+	}
+
+	restored := make([]Todo, 0, len(restore))
+	for _, t := range restore {
+		s.markChanged(*t)
+		t.DeletedAt = nil
+		restored = append(restored, *t)
+	}
+	sort.Slice(restored, func(i, j int) bool {
+		return restored[i].ID < restored[j].ID
+	})
+
+	if err := s.commit(ActionRestore, id); err != nil {
+		return nil, fmt.Errorf("failed to save todo: %w", err)
+	}
+
+	return restored, nil
+}
+
+// PurgeTrash permanently removes the todos deleted at or before the given
+// time and returns them. Undo cannot bring purged todos back.
+func (s *Service) PurgeTrash(before time.Time) ([]Todo, error) {
+	var purged []Todo
+	err := s.retry(func() error {
+		var err error
+		purged, err = s.purgeTrash(before)
+		return err
+	})
+	return purged, err
+}
+
+// purgeTrash is PurgeTrash without retrying on conflicts.
+func (s *Service) purgeTrash(before time.Time) ([]Todo, error) {
+	trash, err := s.list(ListOptions{Trash: OnlyTrash})
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []Todo
+	purgedIDs := make(map[int]bool)
+	for _, t := range trash {
+		if t.DeletedAt.After(before) {
+			continue
+		}
+		s.markChanged(*t)
+		purged = append(purged, *t)
+		purgedIDs[t.ID] = true
+		s.todos[t.ID] = nil
+	}
+
+	if len(purged) == 0 {
+		return nil, nil
+	}
+
+	s.forgetJournal(purgedIDs)
+	if err := s.save(ActionPurge); err != nil {
+		return nil, fmt.Errorf("failed to save todos: %w", err)
+	}
+
+	return purged, nil
+}
+
+// saveLastID stores the highest ID given so far, once it grew since it was
+// last loaded or stored. Until an ID is stored, only the stored todos tell
+// which IDs were given, so it is stored before any of them are purged. With
+// other repositories than an IDRepository, the service keeps it.
+func (s *Service) saveLastID() error {
+	if s.lastID == 0 {
+		if err := s.loadNextID(); err != nil {
+			return err
+		}
+	}
+	if s.nextID == 0 || s.nextID-1 <= s.lastID {
+		return nil
+	}
+	if repo, ok := s.repo.(IDRepository); ok {
+		if err := repo.SaveLastID(s.nextID - 1); err != nil {
+			return err
+		}
+	}
+	s.lastID = s.nextID - 1
+	return nil
+}
+
+func (s *Service) loadLastID() error {
+	repo, ok := s.repo.(IDRepository)
+	if !ok {
+		return nil
+	}
+	lastID, err := repo.LoadLastID()
+	if err != nil {
+		return err
+	}
+	s.lastID = lastID
+	return nil
+}
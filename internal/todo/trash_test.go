@@ -0,0 +1,234 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestService_Delete_MovesToTrash(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	for _, description := range []string{"First", "Second"} {
+		if _, err := service.Add(description); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+
+	if err := service.Delete(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(listTodos(t, service)) != 1 {
+		t.Errorf("Expected 1 todo, got %d", len(listTodos(t, service)))
+	}
+	if stats, err := service.GetStats(); err != nil || stats.Total != 1 {
+		t.Errorf("Expected deleted todos to be left out of stats, got total %d", stats.Total)
+	}
+
+	trash := trashTodos(t, service)
+	if len(trash) != 1 || trash[0].ID != 2 {
+		t.Fatalf("Expected todo 2 in the trash, got %v", trash)
+	}
+	if trash[0].DeletedAt == nil {
+		t.Error("DeletedAt should be set")
+	}
+
+	// Deleted todos are stored, and their IDs are not reused after a reload.
+	if len(repo.todos) != 2 {
+		t.Errorf("Expected 2 stored todos, got %d", len(repo.todos))
+	}
+	reloaded := NewService(repo)
+	if len(trashTodos(t, reloaded)) != 1 {
+		t.Errorf("Expected 1 todo in the trash after reload, got %d", len(trashTodos(t, reloaded)))
+	}
+	added, err := reloaded.Add("Third")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if added.ID != 3 {
+		t.Errorf("Expected ID 3, got %d", added.ID)
+	}
+}
+
+func TestService_Restore(t *testing.T) {
+	tests := []struct {
+		name          string
+		cascade       bool
+		restoreID     int
+		expectedIDs   []int
+		expectedTrash int
+		parentOf3     int
+	}{
+		{"single todo", false, 2, []int{1, 2, 3}, 0, 1},
+		{"cascade restores subtasks", true, 2, []int{1, 2, 3}, 0, 2},
+		{"subtask of a deleted todo", true, 3, []int{1, 3}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&MockRepository{})
+			if _, err := service.Add("Root"); err != nil {
+				t.Fatalf("Failed to add todo: %v", err)
+			}
+			if _, err := service.Add("Middle", WithParent(1)); err != nil {
+				t.Fatalf("Failed to add todo: %v", err)
+			}
+			if _, err := service.Add("Leaf", WithParent(2)); err != nil {
+				t.Fatalf("Failed to add todo: %v", err)
+			}
+
+			var opts []DeleteOption
+			if tt.cascade {
+				opts = append(opts, DeleteCascade())
+			}
+			if err := service.Delete(2, opts...); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if _, err := service.Restore(tt.restoreID); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			todos := listTodos(t, service)
+			if len(todos) != len(tt.expectedIDs) {
+				t.Fatalf("Expected %d todos, got %d", len(tt.expectedIDs), len(todos))
+			}
+			for i, id := range tt.expectedIDs {
+				if todos[i].ID != id {
+					t.Errorf("Position %d: expected ID %d, got %d", i, id, todos[i].ID)
+				}
+				if todos[i].DeletedAt != nil {
+					t.Errorf("Todo %d: DeletedAt should be cleared", id)
+				}
+			}
+			if len(trashTodos(t, service)) != tt.expectedTrash {
+				t.Errorf("Expected %d todos in the trash, got %d", tt.expectedTrash, len(trashTodos(t, service)))
+			}
+
+			leaf, err := service.Get(3)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if leaf.ParentID != tt.parentOf3 {
+				t.Errorf("Expected parent %d, got %d", tt.parentOf3, leaf.ParentID)
+			}
+		})
+	}
+}
+
+func TestService_Restore_NotInTrash(t *testing.T) {
+	service := NewService(&MockRepository{})
+	if _, err := service.Add("Active"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+
+	for _, id := range []int{1, 999} {
+		if _, err := service.Restore(id); err == nil {
+			t.Errorf("Expected error restoring todo %d, got nil", id)
+		}
+	}
+}
+
+func TestService_PurgeTrash(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+	for _, description := range []string{"Old", "Recent", "Active"} {
+		if _, err := service.Add(description); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+	for _, id := range []int{1, 2} {
+		if err := service.Delete(id); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	longAgo := time.Now().AddDate(0, 0, -60)
+	repo.todos[0].DeletedAt = &longAgo
+
+	purged, err := service.PurgeTrash(time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(purged) != 1 || purged[0].ID != 1 {
+		t.Errorf("Expected todo 1 to be purged, got %v", purged)
+	}
+	if len(trashTodos(t, service)) != 1 {
+		t.Errorf("Expected 1 todo left in the trash, got %d", len(trashTodos(t, service)))
+	}
+
+	purged, err = service.PurgeTrash(time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(purged) != 1 || len(trashTodos(t, service)) != 0 {
+		t.Errorf("Expected the trash to be empty, got %v", trashTodos(t, service))
+	}
+	if len(repo.todos) != 1 {
+		t.Errorf("Expected 1 stored todo, got %d", len(repo.todos))
+	}
+}
+
+// MockIDRepository is a mock repository that keeps the last ID.
+type MockIDRepository struct {
+	MockRepository
+	lastID int
+}
+
+func (m *MockIDRepository) SaveLastID(id int) error {
+	m.lastID = id
+	return nil
+}
+
+func (m *MockIDRepository) LoadLastID() (int, error) {
+	return m.lastID, nil
+}
+
+func TestService_PurgeTrash_KeepsIDs(t *testing.T) {
+	repo := &MockIDRepository{}
+	service := NewService(repo)
+	for _, description := range []string{"First", "Second"} {
+		if _, err := service.Add(description); err != nil {
+			t.Fatalf("Failed to add todo: %v", err)
+		}
+	}
+	if err := service.Delete(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.PurgeTrash(time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if repo.lastID != 2 {
+		t.Errorf("Expected last ID 2, got %d", repo.lastID)
+	}
+
+	reloaded := NewService(repo)
+	added, err := reloaded.Add("Third")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if added.ID != 3 {
+		t.Errorf("Expected the purged ID not to be reused, got ID %d", added.ID)
+	}
+}
+
+func TestService_PurgeTrash_CannotUndo(t *testing.T) {
+	service := NewService(&MockRepository{})
+	if _, err := service.Add("Purged"); err != nil {
+		t.Fatalf("Failed to add todo: %v", err)
+	}
+	if err := service.Delete(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.PurgeTrash(time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := service.Undo(1); err == nil {
+		t.Error("Expected error undoing a purged todo, got nil")
+	}
+	if len(listTodos(t, service)) != 0 || len(trashTodos(t, service)) != 0 {
+		t.Errorf("Expected the purged todo to stay gone, got %v and trash %v", listTodos(t, service), trashTodos(t, service))
+	}
+}